@@ -5,17 +5,33 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/alerts"
 	"github.com/gabrielmoura/raspController/infra/db"
 	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/infra/i2c"
+	"github.com/gabrielmoura/raspController/infra/mqtt"
+	"github.com/gabrielmoura/raspController/infra/pwm"
 	"github.com/gabrielmoura/raspController/infra/routes"
+	"github.com/gabrielmoura/raspController/infra/rules"
+	"github.com/gabrielmoura/raspController/infra/sampler"
+	"github.com/gabrielmoura/raspController/infra/scheduler"
 	"github.com/gabrielmoura/raspController/internal/install"
 	"github.com/gabrielmoura/raspController/pkg/mdns"
 	"github.com/gofiber/fiber/v2"
 )
 
 func main() {
+	// Token management subcommand
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		if err := runToken(os.Args[2:]); err != nil {
+			log.Fatalf("Token command failed: %v", err)
+		}
+		return
+	}
+
 	// Parsing the install flag
 	installFlag := flag.Bool("install", false, "Run the installation process")
 	flag.Parse()
@@ -63,6 +79,41 @@ func run() error {
 		fmt.Println("failed to initialize GPIO: %w", err)
 	}
 
+	// Initialize hardware PWM
+	if err := pwm.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize PWM:", err)
+	}
+
+	// Initialize the I2C buses
+	if err := i2c.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize I2C:", err)
+	}
+
+	// Start the rules
+	if err := rules.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize rules:", err)
+	}
+
+	// Start the schedules
+	if err := scheduler.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize scheduler:", err)
+	}
+
+	// Start the alerts
+	if err := alerts.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize alerts:", err)
+	}
+
+	// Start sampling the metric history
+	if err := sampler.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize sampler:", err)
+	}
+
+	// Bridge to the MQTT broker
+	if err := mqtt.Initialize(ctx); err != nil {
+		log.Println("Warning: Failed to initialize MQTT:", err)
+	}
+
 	// Set mDNS
 	if err := mdns.SetDNS(configs.Conf.AppName, configs.Conf.Port); err != nil {
 		log.Println("Warning: Failed to set mDNS:", err)
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/auth"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+const tokenUsage = `Usage:
+  raspc token list
+  raspc token create -name <name> -scopes <scope,...> [-expires <duration>]
+  raspc token delete <name>
+
+Scopes: %s
+The database is locked while the service runs, stop it before managing tokens.
+`
+
+// runToken manages the API tokens from the command line.
+func runToken(args []string) error {
+	if len(args) == 0 {
+		fmt.Printf(tokenUsage, strings.Join(dto.Scopes, ", "))
+		return errors.New("missing token command")
+	}
+
+	if err := configs.LoadConfig(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := db.Initialize(context.Background()); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.DB.Close()
+
+	switch args[0] {
+	case "list":
+		return listTokens()
+	case "create":
+		return createToken(args[1:])
+	case "delete":
+		if len(args) != 2 {
+			return errors.New("usage: raspc token delete <name>")
+		}
+		if err := auth.Delete(args[1]); err != nil {
+			return err
+		}
+		fmt.Println("Token deleted:", args[1])
+		return nil
+	default:
+		fmt.Printf(tokenUsage, strings.Join(dto.Scopes, ", "))
+		return fmt.Errorf("unknown token command %q", args[0])
+	}
+}
+
+func listTokens() error {
+	tokens, err := auth.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPES\tEXPIRES\tLAST USED")
+	for _, t := range tokens {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Name, strings.Join(t.Scopes, ","), formatTime(t.ExpiresAt), formatTime(t.LastUsed))
+	}
+	return w.Flush()
+}
+
+func createToken(args []string) error {
+	fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+	name := fs.String("name", "", "Name of the token")
+	scopes := fs.String("scopes", "", "Comma separated list of scopes")
+	expires := fs.String("expires", "", "Lifetime of the token, e.g. 720h (default never)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := dto.TokenRequest{Name: *name, ExpiresIn: *expires}
+	if *scopes != "" {
+		req.Scopes = strings.Split(*scopes, ",")
+	}
+	if err := req.Validation(); err != nil {
+		return err
+	}
+
+	secret, token, err := auth.Create(req)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Token %s created with scopes %s\n", token.Name, strings.Join(token.Scopes, ","))
+	fmt.Println("Secret (shown only once):", secret)
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
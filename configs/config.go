@@ -2,32 +2,103 @@ package configs
 
 import (
 	"errors"
+	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Cfg struct {
 	AuthToken  string `mapstructure:"AUTH_TOKEN" validate:"required"`
+	AuthRead   bool   `mapstructure:"AUTH_READ"`
 	AppName    string `mapstructure:"APP_NAME"`
 	DBDir      string `mapstructure:"DB_DIR"`
 	Port       int    `mapstructure:"PORT"`
 	ShareDir   string `mapstructure:"SHARE_DIR"`
 	TimeFormat string `mapstructure:"TIME_FORMAT"`
 	TimeZone   string `mapstructure:"TIME_ZONE"`
+	PWMRoot    string `mapstructure:"PWM_ROOT"`
+	GPIOChip   string `mapstructure:"GPIO_CHIP"`
+
+	DBMergeCron string `mapstructure:"DB_MERGE_CRON"` // when expired and deleted entries are removed from disk, empty to never
+
+	GPIODriver   string   `mapstructure:"GPIO_DRIVER"`    // gpiocdev, sim or gpio-sim
+	GPIOSimLines int      `mapstructure:"GPIO_SIM_LINES"` // lines of the simulated chip
+	GPIOSimNames []string `mapstructure:"GPIO_SIM_NAMES"` // names of the simulated lines, GPIO<offset> by default
+
+	I2CBackend string `mapstructure:"I2C_BACKEND"` // i2c-dev or sim
+
+	AuditRetention  time.Duration `mapstructure:"AUDIT_RETENTION"`   // age of the oldest pin change kept, 0 to keep all
+	AuditMaxEntries int           `mapstructure:"AUDIT_MAX_ENTRIES"` // number of pin changes kept, 0 for no limit
+
+	SampleInterval time.Duration `mapstructure:"SAMPLE_INTERVAL"` // interval between two samples of the metric history, 0 to disable
+
+	AlertNotifiers []NotifierCfg `mapstructure:"ALERT_NOTIFIERS"`
+
+	MQTTBroker            string        `mapstructure:"MQTT_BROKER"`    // e.g. tcp://localhost:1883, empty to disable the bridge
+	MQTTClientID          string        `mapstructure:"MQTT_CLIENT_ID"` // default <APP_NAME>-<hostname>
+	MQTTUsername          string        `mapstructure:"MQTT_USERNAME"`
+	MQTTPassword          string        `mapstructure:"MQTT_PASSWORD"`
+	MQTTTopic             string        `mapstructure:"MQTT_TOPIC"`              // base topic, default raspc/<hostname>
+	MQTTTelemetryInterval time.Duration `mapstructure:"MQTT_TELEMETRY_INTERVAL"` // interval between two telemetry publications, 0 to disable
+	MQTTDiscovery         bool          `mapstructure:"MQTT_DISCOVERY"`          // publish Home Assistant discovery payloads
+	MQTTDiscoveryPrefix   string        `mapstructure:"MQTT_DISCOVERY_PREFIX"`
+}
+
+// NotifierCfg configures a notifier of the alerts. Only the fields of its type are used.
+type NotifierCfg struct {
+	Name string `mapstructure:"NAME"`
+	Type string `mapstructure:"TYPE"` // webhook, smtp or mqtt
+
+	URL string `mapstructure:"URL"` // webhook: URL posted to; mqtt: broker, e.g. tcp://localhost:1883
+
+	Host     string   `mapstructure:"HOST"` // smtp
+	Port     int      `mapstructure:"PORT"` // smtp, default 587
+	Username string   `mapstructure:"USERNAME"`
+	Password string   `mapstructure:"PASSWORD"`
+	From     string   `mapstructure:"FROM"`
+	To       []string `mapstructure:"TO"`
+
+	Topic  string `mapstructure:"TOPIC"` // mqtt, each alert is published to <TOPIC>/<alert>, default <APP_NAME>/alerts
+	QoS    byte   `mapstructure:"QOS"`
+	Retain bool   `mapstructure:"RETAIN"`
 }
 
 var Conf *Cfg
 
+// Location returns the configured time zone, or the local one if it is invalid.
+func (c *Cfg) Location() *time.Location {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		log.Printf("Invalid TIME_ZONE %q, using local time: %v", c.TimeZone, err)
+		return time.Local
+	}
+	return loc
+}
+
 func LoadConfig() error {
 	var cfg Cfg
 	vip := viper.New()
 
 	// Setting default values
 	vip.SetDefault("PORT", 8000)
+	vip.SetDefault("AUTH_READ", false)
 	vip.SetDefault("DB_DIR", "/tmp/raspc")
+	vip.SetDefault("DB_MERGE_CRON", "@daily")
 	vip.SetDefault("APP_NAME", "RaspController")
 	vip.SetDefault("TIME_FORMAT", "02-Jan-2006")
 	vip.SetDefault("TIME_ZONE", "America/Sao_Paulo")
+	vip.SetDefault("PWM_ROOT", "/sys/class/pwm")
+	vip.SetDefault("GPIO_CHIP", "gpiochip0")
+	vip.SetDefault("GPIO_DRIVER", "gpiocdev")
+	vip.SetDefault("GPIO_SIM_LINES", 54)
+	vip.SetDefault("I2C_BACKEND", "i2c-dev")
+	vip.SetDefault("AUDIT_RETENTION", "720h")
+	vip.SetDefault("AUDIT_MAX_ENTRIES", 10000)
+	vip.SetDefault("SAMPLE_INTERVAL", "10s")
+	vip.SetDefault("MQTT_TELEMETRY_INTERVAL", "30s")
+	vip.SetDefault("MQTT_DISCOVERY", true)
+	vip.SetDefault("MQTT_DISCOVERY_PREFIX", "homeassistant")
 
 	// Reading the conf.yml configuration file
 	vip.SetConfigName("conf")
@@ -0,0 +1,201 @@
+// Package alerts watches system metrics against thresholds and notifies
+// webhooks, mailboxes and MQTT brokers when an alert fires or resolves.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+// ErrInvalidAlert is returned when an alert references a metric or notifier that does not exist.
+var ErrInvalidAlert = errors.New("invalid alert")
+
+var (
+	watchers = make(map[string]*watcher) // keyed by alert name
+	mu       sync.Mutex                  // guards watchers and the alerts in the database
+	stateMu  sync.Mutex                  // guards the alert states in the database
+	once     sync.Once
+	host     string // reported in the notifications
+)
+
+// AlertStatus is an alert rule together with its state.
+type AlertStatus struct {
+	dto.AlertRule
+	dto.AlertState
+}
+
+// Initialize creates the notifiers of the configuration and starts watching
+// every enabled alert stored in the database, resuming its stored state.
+func Initialize(ctx context.Context) error {
+	var initErr error
+	once.Do(func() {
+		host, _ = os.Hostname()
+		if err := loadNotifiers(); err != nil {
+			log.Println("Alerts: Error loading notifiers:", err)
+		}
+
+		stored, err := db.GetAlerts()
+		if err != nil {
+			initErr = fmt.Errorf("Error reading alerts: %w", err)
+			return
+		}
+		states, err := db.GetAlertStates()
+		if err != nil {
+			initErr = fmt.Errorf("Error reading alert states: %w", err)
+			return
+		}
+
+		mu.Lock()
+		for _, alert := range stored {
+			start(alert, states[alert.Name])
+		}
+		mu.Unlock()
+		log.Printf("Alerts: %d alerts and %d notifiers loaded", len(stored), len(notifiers))
+
+		go func() {
+			<-ctx.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for name := range watchers {
+				stop(name)
+			}
+		}()
+	})
+	return initErr
+}
+
+// isFlag reports whether metric is a get_throttled flag rather than a metric.
+func isFlag(metric string) bool {
+	for _, flag := range vchiq.ThrottledFlags {
+		if flag.Name == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns the current value of the metric or flag of an alert.
+func read(metric string) (float64, error) {
+	if isFlag(metric) {
+		return vchiq.ReadFlag(metric)
+	}
+	return vchiq.ReadMetric(metric)
+}
+
+// check validates the parts of alert that depend on the system.
+func check(alert dto.AlertRule) error {
+	known := isFlag(alert.Metric)
+	for _, m := range vchiq.Metrics {
+		known = known || m == alert.Metric
+	}
+	if !known {
+		return fmt.Errorf("%w: %w: %s", ErrInvalidAlert, vchiq.ErrUnknownMetric, alert.Metric)
+	}
+	for _, name := range alert.Notifiers {
+		if _, ok := notifiers[name]; !ok {
+			return fmt.Errorf("%w: %w: %s", ErrInvalidAlert, ErrUnknownNotifier, name)
+		}
+	}
+	return nil
+}
+
+// List returns every alert with its state, sorted by name.
+func List() ([]AlertStatus, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetAlerts()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]AlertStatus, 0, len(stored))
+	for _, alert := range stored {
+		list = append(list, status(alert))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns an alert with its state.
+func Get(name string) (AlertStatus, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetAlerts()
+	if err != nil {
+		return AlertStatus{}, err
+	}
+	alert, ok := stored[name]
+	if !ok {
+		return AlertStatus{}, db.ErrAlertNotFound
+	}
+	return status(alert), nil
+}
+
+// status returns alert with its state.
+// The caller must hold mu.
+func status(alert dto.AlertRule) AlertStatus {
+	s := AlertStatus{AlertRule: alert, AlertState: dto.AlertState{State: dto.AlertInactive}}
+	if w, ok := watchers[alert.Name]; ok {
+		s.AlertState = w.status()
+	}
+	return s
+}
+
+// Set creates or replaces an alert. A replaced alert keeps its state, which
+// the new condition resolves on its next reading if it no longer holds.
+func Set(alert dto.AlertRule) (AlertStatus, error) {
+	if err := check(alert); err != nil {
+		return AlertStatus{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := db.SetAlert(alert); err != nil {
+		return AlertStatus{}, err
+	}
+	var previous dto.AlertState
+	if w, ok := watchers[alert.Name]; ok {
+		previous = w.status()
+	}
+	stop(alert.Name)
+	if alert.Disabled {
+		previous = dto.AlertState{State: dto.AlertInactive, Since: time.Now()}
+		save(alert.Name, previous)
+	}
+	start(alert, previous)
+	return status(alert), nil
+}
+
+// Delete stops and removes an alert and its state. No notification is sent,
+// even if it was firing.
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Stopped first, so that the watcher does not store the state again.
+	stop(name)
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return db.DeleteAlert(name)
+}
+
+// save stores the state of the alert name.
+func save(name string, state dto.AlertState) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if err := db.SetAlertState(name, state); err != nil {
+		log.Printf("Alerts: Error storing the state of %s: %v", name, err)
+	}
+}
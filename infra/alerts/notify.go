@@ -0,0 +1,302 @@
+package alerts
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// Notifier types accepted in ALERT_NOTIFIERS.
+const (
+	NotifierWebhook = "webhook"
+	NotifierSMTP    = "smtp"
+	NotifierMQTT    = "mqtt"
+)
+
+// notifyTimeout bounds the delivery of a notification.
+const notifyTimeout = 10 * time.Second
+
+// ErrUnknownNotifier is returned for notifier names not in ALERT_NOTIFIERS.
+var ErrUnknownNotifier = errors.New("unknown notifier")
+
+// Notifier delivers the notifications of the alerts.
+type Notifier interface {
+	Notify(n dto.AlertNotification) error
+}
+
+// NotifierInfo describes a configured notifier.
+type NotifierInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+var (
+	notifiers     = make(map[string]Notifier) // keyed by name, set once by Initialize
+	notifierTypes = make(map[string]string)
+)
+
+// newNotifier creates the notifier configured by cfg.
+func newNotifier(cfg configs.NotifierCfg) (Notifier, error) {
+	switch cfg.Type {
+	case NotifierWebhook:
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("webhook notifier requires an http or https URL")
+		}
+		return &webhookNotifier{url: cfg.URL, client: &http.Client{Timeout: notifyTimeout}}, nil
+	case NotifierSMTP:
+		if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, errors.New("smtp notifier requires a host, from and to")
+		}
+		if cfg.Port == 0 {
+			cfg.Port = 587
+		}
+		return &smtpNotifier{cfg: cfg}, nil
+	case NotifierMQTT:
+		if cfg.URL == "" {
+			return nil, errors.New("mqtt notifier requires the URL of the broker")
+		}
+		if cfg.Topic == "" {
+			cfg.Topic = configs.Conf.AppName + "/alerts"
+		}
+		return newMQTTNotifier(cfg), nil
+	default:
+		return nil, fmt.Errorf("invalid notifier type %q, use %s, %s or %s", cfg.Type, NotifierWebhook, NotifierSMTP, NotifierMQTT)
+	}
+}
+
+// loadNotifiers creates the notifiers of ALERT_NOTIFIERS. A notifier with an
+// invalid configuration is left out and reported in the returned error.
+func loadNotifiers() error {
+	var errs []error
+	for _, cfg := range configs.Conf.AlertNotifiers {
+		if cfg.Name == "" {
+			errs = append(errs, errors.New("notifier without a name"))
+			continue
+		}
+		if _, ok := notifiers[cfg.Name]; ok {
+			errs = append(errs, fmt.Errorf("notifier %s: duplicate name", cfg.Name))
+			continue
+		}
+		n, err := newNotifier(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", cfg.Name, err))
+			continue
+		}
+		notifiers[cfg.Name] = n
+		notifierTypes[cfg.Name] = cfg.Type
+	}
+	return errors.Join(errs...)
+}
+
+// Notifiers returns the configured notifiers, sorted by name.
+func Notifiers() []NotifierInfo {
+	list := make([]NotifierInfo, 0, len(notifiers))
+	for name := range notifiers {
+		list = append(list, NotifierInfo{Name: name, Type: notifierTypes[name]})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// TestNotifier sends a test notification through the notifier name.
+func TestNotifier(name string) error {
+	n, ok := notifiers[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownNotifier, name)
+	}
+	return n.Notify(dto.AlertNotification{
+		Alert:  "test",
+		Status: dto.AlertFiring,
+		Host:   host,
+		Time:   time.Now(),
+		Test:   true,
+	})
+}
+
+// notify sends n through the notifiers of alert, every notifier if it names none.
+func notify(alert dto.AlertRule, n dto.AlertNotification) error {
+	names := alert.Notifiers
+	if len(names) == 0 {
+		for name := range notifiers {
+			names = append(names, name)
+		}
+	}
+
+	var errs []error
+	for _, name := range names {
+		notifier, ok := notifiers[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownNotifier, name))
+			continue
+		}
+		if err := notifier.Notify(n); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// summary is the one-line description of a notification.
+func summary(n dto.AlertNotification) string {
+	if n.Test {
+		return fmt.Sprintf("Test notification from %s", n.Host)
+	}
+	return fmt.Sprintf("[%s] %s on %s: %s = %g (%s %g)",
+		strings.ToUpper(n.Status), n.Alert, n.Host, n.Metric, n.Value, n.Operator, n.Threshold)
+}
+
+// webhookNotifier posts the notifications as JSON to a URL.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookNotifier) Notify(n dto.AlertNotification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// smtpNotifier mails the notifications. STARTTLS is used when the server offers it.
+type smtpNotifier struct {
+	cfg configs.NotifierCfg
+}
+
+func (s *smtpNotifier) Notify(n dto.AlertNotification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", summary(n))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\n", summary(n))
+	if !n.Test {
+		fmt.Fprintf(&msg, "Alert:     %s\r\nStatus:    %s\r\nSeverity:  %s\r\nHost:      %s\r\n", n.Alert, n.Status, n.Severity, n.Host)
+		fmt.Fprintf(&msg, "Metric:    %s\r\nValue:     %g\r\nCondition: %s %g\r\n", n.Metric, n.Value, n.Operator, n.Threshold)
+	}
+	fmt.Fprintf(&msg, "Time:      %s\r\n", n.Time.Format(time.RFC3339))
+
+	return s.send(msg.Bytes())
+}
+
+// send delivers msg like smtp.SendMail, but within notifyTimeout so that an
+// unresponsive server does not hold the notification forever.
+func (s *smtpNotifier) send(msg []byte) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	conn, err := net.DialTimeout("tcp", addr, notifyTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(notifyTimeout)); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range s.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// mqttNotifier publishes the notifications as JSON to <topic>/<alert>. The
+// broker is connected to on the first notification.
+type mqttNotifier struct {
+	topic  string
+	qos    byte
+	retain bool
+
+	mu     sync.Mutex // serializes the connection
+	client mqtt.Client
+}
+
+func newMQTTNotifier(cfg configs.NotifierCfg) *mqttNotifier {
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.URL).
+		SetClientID(fmt.Sprintf("%s-%s", strings.ToLower(strings.ReplaceAll(configs.Conf.AppName, " ", "-")), cfg.Name)).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetConnectTimeout(notifyTimeout).
+		SetAutoReconnect(true)
+	return &mqttNotifier{topic: cfg.Topic, qos: cfg.QoS, retain: cfg.Retain, client: mqtt.NewClient(opts)}
+}
+
+func (m *mqttNotifier) Notify(n dto.AlertNotification) error {
+	m.mu.Lock()
+	if !m.client.IsConnected() {
+		if err := wait(m.client.Connect()); err != nil {
+			m.mu.Unlock()
+			return fmt.Errorf("error connecting to the broker: %w", err)
+		}
+	}
+	m.mu.Unlock()
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return wait(m.client.Publish(m.topic+"/"+n.Alert, m.qos, m.retain, payload))
+}
+
+// wait waits for an MQTT operation to complete.
+func wait(token mqtt.Token) error {
+	if !token.WaitTimeout(notifyTimeout) {
+		return errors.New("timeout")
+	}
+	return token.Error()
+}
@@ -0,0 +1,81 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var (
+		method, contentType string
+		received            dto.AlertNotification
+		status              = http.StatusNoContent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType = r.Method, r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decoding the notification: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	n, err := newNotifier(configs.NotifierCfg{Name: "hook", Type: NotifierWebhook, URL: srv.URL + "/alerts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := dto.AlertNotification{
+		Alert:     "hot",
+		Status:    dto.AlertFiring,
+		Severity:  "critical",
+		Host:      "pi",
+		Metric:    "cpu_temp",
+		Value:     81.5,
+		Operator:  ">",
+		Threshold: 80,
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := n.Notify(sent); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost || contentType != "application/json" {
+		t.Errorf("request = %s with %s, want POST with application/json", method, contentType)
+	}
+	if !received.Time.Equal(sent.Time) {
+		t.Errorf("time = %s, want %s", received.Time, sent.Time)
+	}
+	received.Time = sent.Time
+	if received != sent {
+		t.Errorf("received %+v, want %+v", received, sent)
+	}
+
+	status = http.StatusInternalServerError
+	if err := n.Notify(sent); err == nil {
+		t.Error("a 500 response was not reported")
+	}
+
+	srv.Close()
+	if err := n.Notify(sent); err == nil {
+		t.Error("an unreachable webhook was not reported")
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	for _, cfg := range []configs.NotifierCfg{
+		{Name: "hook", Type: NotifierWebhook, URL: "ftp://example.com"},
+		{Name: "hook", Type: NotifierWebhook},
+		{Name: "mail", Type: NotifierSMTP, Host: "smtp.example.com", From: "pi@example.com"},
+		{Name: "broker", Type: NotifierMQTT},
+		{Name: "pager", Type: "pager"},
+	} {
+		if _, err := newNotifier(cfg); err == nil {
+			t.Errorf("newNotifier(%+v) succeeded", cfg)
+		}
+	}
+}
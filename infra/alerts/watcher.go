@@ -0,0 +1,155 @@
+package alerts
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// watcher reads the metric of an alert and moves it between its states.
+type watcher struct {
+	alert    dto.AlertRule
+	forDur   time.Duration
+	interval time.Duration
+	stopCh   chan struct{}
+	done     chan struct{}
+
+	mu    sync.Mutex // guards state
+	state dto.AlertState
+}
+
+// start watches alert from state unless it is disabled.
+// The caller must hold mu.
+func start(alert dto.AlertRule, state dto.AlertState) {
+	if alert.Disabled {
+		return
+	}
+	if state.State == "" {
+		state = dto.AlertState{State: dto.AlertInactive, Since: time.Now()}
+	}
+	forDur, _ := alert.ForPeriod()
+	interval, _ := alert.IntervalPeriod()
+	w := &watcher{
+		alert:    alert,
+		forDur:   forDur,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+		state:    state,
+	}
+	watchers[alert.Name] = w
+	go w.run()
+}
+
+// stop ends the watcher of the alert name, if running.
+// The caller must hold mu.
+func stop(name string) {
+	w, ok := watchers[name]
+	if !ok {
+		return
+	}
+	delete(watchers, name)
+	close(w.stopCh)
+	<-w.done
+}
+
+func (w *watcher) status() dto.AlertState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+func (w *watcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		value, err := read(w.alert.Metric)
+		w.evaluate(value, err, time.Now())
+		select {
+		case <-ticker.C:
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// evaluate moves the alert to its next state given a reading of its metric:
+// inactive to pending once the condition holds, pending to firing once it held
+// for the for duration, and firing back to inactive once the metric is past
+// the threshold by the hysteresis. Readings failing leave the state as is.
+func (w *watcher) evaluate(value float64, err error, now time.Time) {
+	w.mu.Lock()
+	s := w.state
+	if err != nil {
+		s.Error = err.Error()
+		w.state = s
+		w.mu.Unlock()
+		return
+	}
+	s.Error = ""
+	s.Value = &value
+
+	var notification string
+	switch s.State {
+	case dto.AlertFiring:
+		if w.alert.Resolved(value) {
+			s.State, s.Since, s.ResolvedAt = dto.AlertInactive, now, &now
+			notification = dto.AlertResolved
+		}
+	case dto.AlertPending:
+		if !w.alert.Compare(value) {
+			s.State, s.Since = dto.AlertInactive, now
+		} else if now.Sub(s.Since) >= w.forDur {
+			s.State, s.Since, s.FiredAt = dto.AlertFiring, now, &now
+			notification = dto.AlertFiring
+		}
+	default:
+		if w.alert.Compare(value) {
+			s.State, s.Since = dto.AlertPending, now
+			if w.forDur == 0 {
+				s.State, s.FiredAt = dto.AlertFiring, &now
+				notification = dto.AlertFiring
+			}
+		}
+	}
+	changed := s.State != w.state.State
+	w.state = s
+	w.mu.Unlock()
+
+	if changed {
+		save(w.alert.Name, s)
+	}
+	if notification != "" {
+		log.Printf("Alerts: %s %s (%s = %g)", w.alert.Name, notification, w.alert.Metric, value)
+		// Notifications are sent apart so that a slow notifier does not delay the readings.
+		go w.notify(dto.AlertNotification{
+			Alert:     w.alert.Name,
+			Status:    notification,
+			Severity:  w.alert.Severity,
+			Host:      host,
+			Metric:    w.alert.Metric,
+			Value:     value,
+			Operator:  w.alert.Operator,
+			Threshold: w.alert.Threshold,
+			Time:      now,
+		})
+	}
+}
+
+// notify sends n and records the outcome in the state of the alert.
+func (w *watcher) notify(n dto.AlertNotification) {
+	err := notify(w.alert, n)
+	if err != nil {
+		log.Printf("Alerts: Error notifying %s: %v", w.alert.Name, err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state.NotifyErr = ""
+	if err != nil {
+		w.state.NotifyErr = err.Error()
+	}
+}
@@ -0,0 +1,199 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// recorder is a notifier keeping the notifications it is sent.
+type recorder struct {
+	ch chan dto.AlertNotification
+}
+
+func (r *recorder) Notify(n dto.AlertNotification) error {
+	r.ch <- n
+	return nil
+}
+
+// setupAlerts opens a fresh database and makes a recorder the only notifier.
+func setupAlerts(t *testing.T) *recorder {
+	t.Helper()
+	configs.Conf = &configs.Cfg{DBDir: t.TempDir()}
+	if err := db.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.DB.Close() })
+
+	rec := &recorder{ch: make(chan dto.AlertNotification, 10)}
+	defaultNotifiers := notifiers
+	notifiers = map[string]Notifier{"rec": rec}
+	t.Cleanup(func() { notifiers = defaultNotifiers })
+	return rec
+}
+
+func TestEvaluate(t *testing.T) {
+	errRead := errors.New("vcgencmd failed")
+
+	type step struct {
+		at     time.Duration // since the start of the test
+		value  float64
+		err    error
+		state  string
+		notify string // status of the notification sent, if any
+	}
+	tests := []struct {
+		name  string
+		alert dto.AlertRule
+		from  string // initial state
+		steps []step
+	}{
+		{
+			name:  "inactive below the threshold",
+			alert: dto.AlertRule{Operator: ">", Threshold: 80},
+			from:  dto.AlertInactive,
+			steps: []step{
+				{value: 50, state: dto.AlertInactive},
+				{at: time.Minute, value: 80, state: dto.AlertInactive},
+			},
+		},
+		{
+			name:  "fires at once without a for duration",
+			alert: dto.AlertRule{Operator: ">", Threshold: 80},
+			from:  dto.AlertInactive,
+			steps: []step{
+				{value: 81, state: dto.AlertFiring, notify: dto.AlertFiring},
+				{at: time.Minute, value: 90, state: dto.AlertFiring},
+			},
+		},
+		{
+			name:  "pending for the for duration",
+			alert: dto.AlertRule{Operator: ">=", Threshold: 80, For: "1m"},
+			from:  dto.AlertInactive,
+			steps: []step{
+				{value: 80, state: dto.AlertPending},
+				{at: 30 * time.Second, value: 85, state: dto.AlertPending},
+				{at: 59 * time.Second, value: 85, state: dto.AlertPending},
+				{at: time.Minute, value: 85, state: dto.AlertFiring, notify: dto.AlertFiring},
+			},
+		},
+		{
+			name:  "pending back to inactive",
+			alert: dto.AlertRule{Operator: ">", Threshold: 80, For: "1m"},
+			from:  dto.AlertInactive,
+			steps: []step{
+				{value: 90, state: dto.AlertPending},
+				{at: 30 * time.Second, value: 70, state: dto.AlertInactive},
+				// The for duration starts again from the next time the condition holds.
+				{at: 40 * time.Second, value: 90, state: dto.AlertPending},
+				{at: 90 * time.Second, value: 90, state: dto.AlertPending},
+				{at: 100 * time.Second, value: 90, state: dto.AlertFiring, notify: dto.AlertFiring},
+			},
+		},
+		{
+			name:  "resolves past the hysteresis",
+			alert: dto.AlertRule{Operator: ">", Threshold: 80, Hysteresis: 5},
+			from:  dto.AlertFiring,
+			steps: []step{
+				{value: 79, state: dto.AlertFiring},
+				{at: time.Second, value: 76, state: dto.AlertFiring},
+				{at: 2 * time.Second, value: 75, state: dto.AlertInactive, notify: dto.AlertResolved},
+				{at: 3 * time.Second, value: 79, state: dto.AlertInactive},
+			},
+		},
+		{
+			name:  "resolves past the hysteresis below the threshold",
+			alert: dto.AlertRule{Operator: "<", Threshold: 10, Hysteresis: 2},
+			from:  dto.AlertInactive,
+			steps: []step{
+				{value: 9, state: dto.AlertFiring, notify: dto.AlertFiring},
+				{at: time.Second, value: 11, state: dto.AlertFiring},
+				{at: 2 * time.Second, value: 12, state: dto.AlertInactive, notify: dto.AlertResolved},
+			},
+		},
+		{
+			name:  "read errors keep the state",
+			alert: dto.AlertRule{Operator: ">", Threshold: 80, For: "1m"},
+			from:  dto.AlertInactive,
+			steps: []step{
+				{err: errRead, state: dto.AlertInactive},
+				{at: time.Second, value: 90, state: dto.AlertPending},
+				{at: 2 * time.Minute, err: errRead, state: dto.AlertPending},
+				{at: 3 * time.Minute, value: 90, state: dto.AlertFiring, notify: dto.AlertFiring},
+				{at: 4 * time.Minute, err: errRead, state: dto.AlertFiring},
+				{at: 5 * time.Minute, value: 20, state: dto.AlertInactive, notify: dto.AlertResolved},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := setupAlerts(t)
+			start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			tt.alert.Name, tt.alert.Metric = "alert", "cpu_temp"
+			forDur, _ := tt.alert.ForPeriod()
+			w := &watcher{alert: tt.alert, forDur: forDur, state: dto.AlertState{State: tt.from, Since: start}}
+
+			var last *float64
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				w.evaluate(s.value, s.err, now)
+				state := w.status()
+
+				if state.State != s.state {
+					t.Fatalf("step %d: state = %s, want %s", i, state.State, s.state)
+				}
+				if s.err != nil {
+					if state.Error != s.err.Error() || state.Value != last {
+						t.Errorf("step %d: error %q, value %v, want %q and the last value", i, state.Error, state.Value, s.err)
+					}
+				} else {
+					if state.Error != "" || state.Value == nil || *state.Value != s.value {
+						t.Errorf("step %d: error %q, value %v, want %g", i, state.Error, state.Value, s.value)
+					}
+					last = state.Value
+				}
+
+				if s.notify == "" {
+					select {
+					case n := <-rec.ch:
+						t.Fatalf("step %d: unexpected notification %+v", i, n)
+					case <-time.After(10 * time.Millisecond):
+					}
+					continue
+				}
+				select {
+				case n := <-rec.ch:
+					if n.Status != s.notify || n.Value != s.value || !n.Time.Equal(now) || n.Alert != "alert" {
+						t.Errorf("step %d: notification %+v, want %s of %g", i, n, s.notify, s.value)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("step %d: no %s notification", i, s.notify)
+				}
+				switch s.notify {
+				case dto.AlertFiring:
+					if state.FiredAt == nil || !state.FiredAt.Equal(now) {
+						t.Errorf("step %d: fired at %v, want %s", i, state.FiredAt, now)
+					}
+				case dto.AlertResolved:
+					if state.ResolvedAt == nil || !state.ResolvedAt.Equal(now) {
+						t.Errorf("step %d: resolved at %v, want %s", i, state.ResolvedAt, now)
+					}
+				}
+			}
+
+			// Every change of state is stored, so that it survives a restart.
+			want := tt.steps[len(tt.steps)-1].state
+			if stored, err := db.GetAlertStates(); err != nil {
+				t.Fatal(err)
+			} else if want != tt.from && stored["alert"].State != want {
+				t.Errorf("stored state = %+v, want %s", stored["alert"], want)
+			}
+		})
+	}
+}
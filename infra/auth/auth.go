@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// lastUsedResolution limits how often the last used time of a token is written.
+const lastUsedResolution = time.Minute
+
+// RootTokenName is the name given to the AUTH_TOKEN from the configuration.
+const RootTokenName = "root"
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+	ErrTokenExists  = errors.New("token already exists")
+)
+
+var mu sync.Mutex // serializes changes to the stored tokens
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create generates a new token and stores its hash. The secret is only
+// returned here and cannot be recovered later.
+func Create(req dto.TokenRequest) (string, dto.Token, error) {
+	expiry, err := req.Expiry()
+	if err != nil {
+		return "", dto.Token{}, err
+	}
+	if req.Name == RootTokenName {
+		return "", dto.Token{}, ErrTokenExists
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", dto.Token{}, fmt.Errorf("AUTH: Error generating token: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+
+	token := dto.Token{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now(),
+	}
+	if expiry > 0 {
+		expiresAt := token.CreatedAt.Add(expiry)
+		token.ExpiresAt = &expiresAt
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	tokens, err := db.GetTokens()
+	if err != nil {
+		return "", dto.Token{}, err
+	}
+	if _, ok := tokens[req.Name]; ok {
+		return "", dto.Token{}, ErrTokenExists
+	}
+	if err := db.SetToken(db.TokenRecord{Token: token, Hash: hash(secret)}); err != nil {
+		return "", dto.Token{}, err
+	}
+	return secret, token, nil
+}
+
+// Verify returns the token matching secret, recording when it was used.
+// The AUTH_TOKEN from the configuration is accepted as an admin token.
+func Verify(secret string) (dto.Token, error) {
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(configs.Conf.AuthToken)) == 1 {
+		return dto.Token{Name: RootTokenName, Scopes: []string{dto.ScopeAdmin}}, nil
+	}
+
+	presented := []byte(hash(secret))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	tokens, err := db.GetTokens()
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	var match *db.TokenRecord
+	for _, record := range tokens {
+		// Compare against every token so the time taken does not reveal a match.
+		if subtle.ConstantTimeCompare(presented, []byte(record.Hash)) == 1 {
+			record := record
+			match = &record
+		}
+	}
+	if match == nil {
+		return dto.Token{}, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if match.Expired(now) {
+		return dto.Token{}, ErrExpiredToken
+	}
+	if match.LastUsed == nil || now.Sub(*match.LastUsed) >= lastUsedResolution {
+		match.LastUsed = &now
+		if err := db.SetToken(*match); err != nil {
+			return dto.Token{}, err
+		}
+	}
+	return match.Token, nil
+}
+
+// List returns every stored token sorted by name, without their hashes.
+func List() ([]dto.Token, error) {
+	tokens, err := db.GetTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]dto.Token, 0, len(tokens))
+	for _, record := range tokens {
+		list = append(list, record.Token)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Delete revokes a token by name.
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return db.DeleteToken(name)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// ErrAlertNotFound is returned when an alert name is not stored.
+var ErrAlertNotFound = errors.New("alert not found")
+
+type AlertMap map[string]dto.AlertRule
+type AlertStateMap map[string]dto.AlertState // keyed by alert name
+
+// GetAlerts returns every stored alert rule keyed by name.
+func GetAlerts() (AlertMap, error) {
+	alerts := make(AlertMap)
+	jsonValue, err := DB.Get([]byte("alert_list"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return alerts, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return alerts, json.Unmarshal(jsonValue, &alerts)
+}
+
+// SetAlert inserts or replaces an alert rule.
+func SetAlert(alert dto.AlertRule) error {
+	alerts, err := GetAlerts()
+	if err != nil {
+		return err
+	}
+	alerts[alert.Name] = alert
+	return SetJson("alert_list", alerts)
+}
+
+// DeleteAlert removes an alert rule and its state by name.
+func DeleteAlert(name string) error {
+	alerts, err := GetAlerts()
+	if err != nil {
+		return err
+	}
+	if _, ok := alerts[name]; !ok {
+		return ErrAlertNotFound
+	}
+	delete(alerts, name)
+	if err := SetJson("alert_list", alerts); err != nil {
+		return err
+	}
+
+	states, err := GetAlertStates()
+	if err != nil {
+		return err
+	}
+	delete(states, name)
+	return SetJson("alert_states", states)
+}
+
+// GetAlertStates returns the stored state of every alert keyed by name.
+func GetAlertStates() (AlertStateMap, error) {
+	states := make(AlertStateMap)
+	jsonValue, err := DB.Get([]byte("alert_states"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return states, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return states, json.Unmarshal(jsonValue, &states)
+}
+
+// SetAlertState stores the state of an alert.
+func SetAlertState(name string, state dto.AlertState) error {
+	states, err := GetAlertStates()
+	if err != nil {
+		return err
+	}
+	states[name] = state
+	return SetJson("alert_states", states)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// The audit log is stored one entry per key, the keys sorting by time.
+const (
+	auditPrefix     = "audit:"
+	auditPruneEvery = 100 // appends between two prunings
+)
+
+// ErrInvalidCursor is returned when a page cursor is not an audit log ID.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var (
+	auditMu     sync.Mutex // guards lastAuditID and auditAdds
+	lastAuditID uint64
+	auditAdds   int
+)
+
+func auditKey(id uint64) []byte {
+	return []byte(fmt.Sprintf("%s%020d", auditPrefix, id))
+}
+
+// AddPinChange appends change to the audit log, giving it an ID ordered by
+// time. The entries beyond AUDIT_RETENTION and AUDIT_MAX_ENTRIES are dropped
+// now and then.
+func AddPinChange(change dto.PinChange) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if lastAuditID == 0 {
+		DB.DescendLessOrEqual(auditKey(math.MaxUint64), func(k []byte, _ []byte) (bool, error) {
+			if bytes.HasPrefix(k, []byte(auditPrefix)) {
+				lastAuditID, _ = strconv.ParseUint(string(k[len(auditPrefix):]), 10, 64)
+			}
+			return false, nil
+		})
+	}
+	id := uint64(change.Time.UnixNano())
+	if id <= lastAuditID {
+		id = lastAuditID + 1
+	}
+	lastAuditID = id
+	change.ID = strconv.FormatUint(id, 10)
+
+	jsonValue, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if err := DB.Put(auditKey(id), jsonValue); err != nil {
+		return err
+	}
+
+	if auditAdds%auditPruneEvery == 0 {
+		if err := pruneAudit(); err != nil {
+			log.Println("Error pruning the audit log:", err)
+		}
+	}
+	auditAdds++
+	return nil
+}
+
+// pruneAudit deletes the entries older than AUDIT_RETENTION and the oldest
+// ones beyond AUDIT_MAX_ENTRIES.
+// The caller must hold auditMu.
+func pruneAudit() error {
+	var keys [][]byte
+	DB.AscendKeys([]byte("^"+auditPrefix), false, func(k []byte) (bool, error) {
+		keys = append(keys, bytes.Clone(k))
+		return true, nil
+	})
+
+	drop := 0
+	if max := configs.Conf.AuditMaxEntries; max > 0 && len(keys) > max {
+		drop = len(keys) - max
+	}
+	if retention := configs.Conf.AuditRetention; retention > 0 {
+		cutoff := auditKey(uint64(time.Now().Add(-retention).UnixNano()))
+		for drop < len(keys) && bytes.Compare(keys[drop], cutoff) < 0 {
+			drop++
+		}
+	}
+
+	for _, key := range keys[:drop] {
+		if err := DB.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPinChanges returns the entries of the audit log selected by q, newest
+// first, and the cursor of the next page, empty on the last page.
+func GetPinChanges(q dto.AuditQuery) ([]dto.PinChange, string, error) {
+	start := auditKey(math.MaxUint64)
+	if q.Before != "" {
+		id, err := strconv.ParseUint(q.Before, 10, 64)
+		if err != nil || id == 0 {
+			return nil, "", ErrInvalidCursor
+		}
+		start = auditKey(id - 1)
+	}
+
+	changes := make([]dto.PinChange, 0)
+	var next string
+	var iterErr error
+	DB.DescendLessOrEqual(start, func(k []byte, v []byte) (bool, error) {
+		if !bytes.HasPrefix(k, []byte(auditPrefix)) {
+			return false, nil
+		}
+		var change dto.PinChange
+		if err := json.Unmarshal(v, &change); err != nil {
+			iterErr = err
+			return false, nil
+		}
+		if !q.To.IsZero() && change.Time.After(q.To) {
+			return true, nil
+		}
+		if !q.From.IsZero() && change.Time.Before(q.From) {
+			return false, nil
+		}
+		if q.Line != nil && change.Line() != *q.Line {
+			return true, nil
+		}
+		if len(changes) == q.Limit {
+			next = changes[len(changes)-1].ID
+			return false, nil
+		}
+		changes = append(changes, change)
+		return true, nil
+	})
+	return changes, next, iterErr
+}
@@ -15,13 +15,19 @@ import (
 // DB represents the database
 var DB *rosedb.DB
 
+// ErrPinNotFound is returned when a pin has no stored configuration.
+var ErrPinNotFound = errors.New("pin not found")
+
 type Map map[string]interface{}
-type PinMap map[int]dto.PinMode
+type PinMap map[string]dto.PinMode // keyed by dto.Line
+type PWMMap map[string]dto.PWMChannel
 
 // Initialize initializes the database.
 func Initialize(ctx context.Context) error {
 	options := rosedb.DefaultOptions
 	options.DirPath = configs.Conf.DBDir
+	// Expired and deleted entries stay on disk until the database is merged.
+	options.AutoMergeCronExpr = configs.Conf.DBMergeCron
 	db, err := rosedb.Open(options)
 	if err != nil {
 		return err
@@ -65,7 +71,18 @@ func GetJsonPin(name string, value *PinMap) error {
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(jsonValue, value)
+	if err := json.Unmarshal(jsonValue, value); err != nil {
+		return err
+	}
+	// Pins stored before multiple chips were supported are keyed by offset only.
+	for key, pin := range *value {
+		if pin.Chip == "" {
+			pin.Chip = configs.Conf.GPIOChip
+			delete(*value, key)
+			(*value)[pin.Line().String()] = pin
+		}
+	}
+	return nil
 }
 
 // SetPin sets the value of a pin in the database.
@@ -77,23 +94,50 @@ func SetPin(pin dto.PinMode) error {
 		log.Println("DB: gpio_list not found")
 	}
 
-	gpios[pin.Pin] = pin
+	gpios[pin.Line().String()] = pin
 
 	return SetJson("gpio_list", gpios)
 }
 
-// GetPin gets the value of a pin from the database.
-func GetPin(pin int) (int, error) {
+// SetPins sets the value of several pins in the database in a single write.
+func SetPins(pins []dto.PinMode) error {
 	gpios := make(PinMap)
 	err := GetJsonPin("gpio_list", &gpios)
 	if err != nil {
-		return 0, fmt.Errorf("error getting pin %d: %s", pin, err.Error())
+		log.Println("DB: gpio_list not found")
 	}
-	value, ok := gpios[pin]
+
+	for _, pin := range pins {
+		gpios[pin.Line().String()] = pin
+	}
+
+	return SetJson("gpio_list", gpios)
+}
+
+// GetPin gets the stored configuration of a line from the database.
+func GetPin(line dto.Line) (dto.PinMode, error) {
+	gpios := make(PinMap)
+	err := GetJsonPin("gpio_list", &gpios)
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return dto.PinMode{}, ErrPinNotFound
+	} else if err != nil {
+		return dto.PinMode{}, fmt.Errorf("error getting pin %s: %s", line, err.Error())
+	}
+	value, ok := gpios[line.String()]
 	if !ok {
-		return 0, errors.New("pin not found")
+		return dto.PinMode{}, ErrPinNotFound
 	}
-	return value.Pin, nil
+	return value, nil
+}
+
+// GetPinMap returns every stored pin configuration keyed by line.
+func GetPinMap() (PinMap, error) {
+	gpios := make(PinMap)
+	err := GetJsonPin("gpio_list", &gpios)
+	if err != nil {
+		return nil, err
+	}
+	return gpios, nil
 }
 
 func GetAllPin() (Map, error) {
@@ -104,3 +148,31 @@ func GetAllPin() (Map, error) {
 	}
 	return gpios, nil
 }
+
+// PWMKey returns the key of a hardware PWM channel in PWMMap.
+func PWMKey(chip, channel int) string {
+	return fmt.Sprintf("%d:%d", chip, channel)
+}
+
+// SetPWM stores the configuration of a hardware PWM channel in the database.
+func SetPWM(ch dto.PWMChannel) error {
+	channels, err := GetPWMMap()
+	if err != nil {
+		log.Println("DB: pwm_list not found")
+		channels = make(PWMMap)
+	}
+
+	channels[PWMKey(ch.Chip, ch.Channel)] = ch
+
+	return SetJson("pwm_list", channels)
+}
+
+// GetPWMMap returns every stored hardware PWM channel keyed by "chip:channel".
+func GetPWMMap() (PWMMap, error) {
+	channels := make(PWMMap)
+	jsonValue, err := DB.Get([]byte("pwm_list"))
+	if err != nil {
+		return nil, err
+	}
+	return channels, json.Unmarshal(jsonValue, &channels)
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// The metric history is stored one sample per key, per metric and tier, the
+// keys sorting by time. The samples expire through the TTL of their keys.
+const historyPrefix = "history:"
+
+// HistoryTier is a resolution at which the metric history is kept.
+type HistoryTier struct {
+	Name      string
+	Step      time.Duration // zero for the raw samples
+	Retention time.Duration
+}
+
+// HistoryTiers lists the tiers of the metric history, finest first.
+var HistoryTiers = []HistoryTier{
+	{Name: "raw", Retention: time.Hour},
+	{Name: "1m", Step: time.Minute, Retention: 24 * time.Hour},
+	{Name: "10m", Step: 10 * time.Minute, Retention: 30 * 24 * time.Hour},
+}
+
+// ErrInvalidRange is returned when the end of a history query precedes its start.
+var ErrInvalidRange = errors.New("invalid range, to is before from")
+
+var (
+	historyMu sync.Mutex                 // guards buckets
+	buckets   = make(map[string]*bucket) // step being aggregated, keyed by metric and tier
+)
+
+// bucket aggregates the samples of a metric during a step of a tier.
+type bucket struct {
+	start    time.Time
+	min, max float64
+	sum      float64
+	count    int
+}
+
+func (b *bucket) add(value float64) {
+	if b.count == 0 || value < b.min {
+		b.min = value
+	}
+	if b.count == 0 || value > b.max {
+		b.max = value
+	}
+	b.sum += value
+	b.count++
+}
+
+func (b *bucket) point() dto.MetricPoint {
+	return dto.MetricPoint{Time: b.start, Avg: b.sum / float64(b.count), Min: b.min, Max: b.max, Count: b.count}
+}
+
+func historyKey(metric string, tier HistoryTier, t time.Time) []byte {
+	return []byte(fmt.Sprintf("%s%s:%s:%020d", historyPrefix, metric, tier.Name, t.UnixNano()))
+}
+
+// encodeBucket packs the minimum, maximum, sum and count of b in 32 bytes.
+func encodeBucket(b *bucket) []byte {
+	buf := make([]byte, 32)
+	binary.BigEndian.PutUint64(buf[0:], math.Float64bits(b.min))
+	binary.BigEndian.PutUint64(buf[8:], math.Float64bits(b.max))
+	binary.BigEndian.PutUint64(buf[16:], math.Float64bits(b.sum))
+	binary.BigEndian.PutUint64(buf[24:], uint64(b.count))
+	return buf
+}
+
+// decodeSample decodes the value stored at key, a raw sample of 8 bytes or a
+// bucket of 32 bytes.
+func decodeSample(key, value []byte) (*bucket, error) {
+	nanos, err := strconv.ParseInt(string(key[strings.LastIndexByte(string(key), ':')+1:]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	b := &bucket{start: time.Unix(0, nanos)}
+	switch len(value) {
+	case 8:
+		b.add(math.Float64frombits(binary.BigEndian.Uint64(value)))
+	case 32:
+		b.min = math.Float64frombits(binary.BigEndian.Uint64(value[0:]))
+		b.max = math.Float64frombits(binary.BigEndian.Uint64(value[8:]))
+		b.sum = math.Float64frombits(binary.BigEndian.Uint64(value[16:]))
+		b.count = int(binary.BigEndian.Uint64(value[24:]))
+	default:
+		return nil, fmt.Errorf("invalid sample of %d bytes at %s", len(value), key)
+	}
+	return b, nil
+}
+
+// putSample stores value at key, expiring at t plus the retention of tier.
+func putSample(key []byte, value []byte, tier HistoryTier, t time.Time) error {
+	ttl := tier.Retention - time.Since(t)
+	if ttl <= 0 {
+		return nil
+	}
+	return DB.PutWithTTL(key, value, ttl)
+}
+
+// AddSample records a sample of metric taken at t in every tier of the history.
+func AddSample(metric string, t time.Time, value float64) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	raw := make([]byte, 8)
+	binary.BigEndian.PutUint64(raw, math.Float64bits(value))
+	if err := putSample(historyKey(metric, HistoryTiers[0], t), raw, HistoryTiers[0], t); err != nil {
+		return err
+	}
+
+	for _, tier := range HistoryTiers[1:] {
+		start := t.Truncate(tier.Step)
+		id := metric + ":" + tier.Name
+		b := buckets[id]
+		if b != nil && !b.start.Equal(start) {
+			if err := putSample(historyKey(metric, tier, b.start), encodeBucket(b), tier, b.start); err != nil {
+				return err
+			}
+			b = nil
+		}
+		if b == nil {
+			b = loadBucket(metric, tier, start)
+			buckets[id] = b
+		}
+		b.add(value)
+	}
+	return nil
+}
+
+// loadBucket returns the step of tier starting at start, resuming the one
+// stored before a restart if any.
+// The caller must hold historyMu.
+func loadBucket(metric string, tier HistoryTier, start time.Time) *bucket {
+	key := historyKey(metric, tier, start)
+	if value, err := DB.Get(key); err == nil {
+		if b, err := decodeSample(key, value); err == nil {
+			return b
+		}
+	}
+	return &bucket{start: start}
+}
+
+// FlushSamples stores the steps still being aggregated, so that they survive
+// a restart.
+func FlushSamples() error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	for _, tier := range HistoryTiers[1:] {
+		for id, b := range buckets {
+			metric, name, _ := strings.Cut(id, ":")
+			if name != tier.Name {
+				continue
+			}
+			if err := putSample(historyKey(metric, tier, b.start), encodeBucket(b), tier, b.start); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PruneHistory deletes the expired samples, which otherwise stay in the index
+// of the database until it is merged. Their expiry is read from their keys, as
+// DeleteExpiredKeys of RoseDB never returns if the last key is not expired.
+func PruneHistory() error {
+	retention := make(map[string]time.Duration, len(HistoryTiers))
+	for _, tier := range HistoryTiers {
+		retention[tier.Name] = tier.Retention
+	}
+
+	now := time.Now()
+	var expired [][]byte
+	DB.AscendKeys([]byte("^"+historyPrefix), false, func(k []byte) (bool, error) {
+		rest, nanos, _ := cutLast(string(k), ':')
+		_, tier, _ := cutLast(rest, ':')
+		t, err := strconv.ParseInt(nanos, 10, 64)
+		if r, ok := retention[tier]; ok && err == nil && now.Sub(time.Unix(0, t)) >= r {
+			expired = append(expired, bytes.Clone(k))
+		}
+		return true, nil
+	})
+	if len(expired) == 0 {
+		return nil
+	}
+
+	batch := DB.NewBatch(rosedb.DefaultBatchOptions)
+	for _, key := range expired {
+		if err := batch.Delete(key); err != nil {
+			_ = batch.Rollback()
+			return err
+		}
+	}
+	return batch.Commit()
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s string, sep byte) (before, after string, found bool) {
+	if i := strings.LastIndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return s, "", false
+}
+
+// GetSamples returns the history selected by q, oldest first, and the tier it
+// was read from: the finest one still holding q.From, or a coarser one if the
+// step of q allows it. Samples are aggregated per step if it is coarser than
+// the tier.
+func GetSamples(q dto.MetricQuery) ([]dto.MetricPoint, HistoryTier, error) {
+	if q.To.Before(q.From) {
+		return nil, HistoryTier{}, ErrInvalidRange
+	}
+
+	// At the precision of the query times, so that the last hour is read raw.
+	age := time.Since(q.From).Truncate(time.Second)
+	tier := HistoryTiers[len(HistoryTiers)-1]
+	for i, t := range HistoryTiers {
+		if age <= t.Retention {
+			tier = t
+			for _, coarser := range HistoryTiers[i+1:] {
+				if coarser.Step <= q.Step {
+					tier = coarser
+				}
+			}
+			break
+		}
+	}
+
+	var samples []*bucket
+	var iterErr error
+	DB.AscendRange(historyKey(q.Metric, tier, q.From.Truncate(tier.Step)), historyKey(q.Metric, tier, q.To),
+		func(k []byte, v []byte) (bool, error) {
+			b, err := decodeSample(k, v)
+			if err != nil {
+				iterErr = err
+				return false, nil
+			}
+			samples = append(samples, b)
+			return true, nil
+		})
+	if iterErr != nil {
+		return nil, tier, iterErr
+	}
+
+	// The step being aggregated is newer than the stored ones.
+	if tier.Step > 0 {
+		historyMu.Lock()
+		if b := buckets[q.Metric+":"+tier.Name]; b != nil && !b.start.After(q.To) {
+			current := *b
+			if n := len(samples); n > 0 && samples[n-1].start.Equal(current.start) {
+				samples = samples[:n-1]
+			}
+			samples = append(samples, &current)
+		}
+		historyMu.Unlock()
+	}
+
+	points := make([]dto.MetricPoint, 0, len(samples))
+	if q.Step <= tier.Step {
+		for _, b := range samples {
+			points = append(points, b.point())
+		}
+		return points, tier, nil
+	}
+
+	var merged *bucket
+	for _, b := range samples {
+		start := b.start.Truncate(q.Step)
+		if merged != nil && !merged.start.Equal(start) {
+			points = append(points, merged.point())
+			merged = nil
+		}
+		if merged == nil {
+			merged = &bucket{start: start, min: b.min, max: b.max}
+		}
+		merged.min = math.Min(merged.min, b.min)
+		merged.max = math.Max(merged.max, b.max)
+		merged.sum += b.sum
+		merged.count += b.count
+	}
+	if merged != nil {
+		points = append(points, merged.point())
+	}
+	return points, tier, nil
+}
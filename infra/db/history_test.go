@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+)
+
+// setupHistory opens a fresh database merged on mergeCron and replaces the
+// history tiers with tiers for the duration of the test.
+func setupHistory(t *testing.T, mergeCron string, tiers []HistoryTier) {
+	t.Helper()
+	configs.Conf = &configs.Cfg{DBDir: t.TempDir(), DBMergeCron: mergeCron}
+	if err := Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = DB.Close() })
+
+	defaultTiers := HistoryTiers
+	HistoryTiers = tiers
+	buckets = make(map[string]*bucket)
+	t.Cleanup(func() { HistoryTiers = defaultTiers })
+}
+
+func countKeys(metric string, tier HistoryTier) int {
+	n := 0
+	DB.AscendKeys([]byte(fmt.Sprintf("^%s%s:%s:", historyPrefix, metric, tier.Name)), false, func([]byte) (bool, error) {
+		n++
+		return true, nil
+	})
+	return n
+}
+
+func TestHistoryTiersBounded(t *testing.T) {
+	tiers := []HistoryTier{
+		{Name: "raw", Retention: 50 * time.Millisecond},
+		{Name: "10ms", Step: 10 * time.Millisecond, Retention: 100 * time.Millisecond},
+		{Name: "50ms", Step: 50 * time.Millisecond, Retention: 200 * time.Millisecond},
+	}
+	setupHistory(t, "", tiers)
+
+	// Samples every millisecond or more for three times the longest retention.
+	const interval = time.Millisecond
+	for end := time.Now().Add(600 * time.Millisecond); time.Now().Before(end); time.Sleep(interval) {
+		if err := AddSample("load1", time.Now(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := FlushSamples(); err != nil {
+		t.Fatal(err)
+	}
+	if err := PruneHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, tier := range tiers {
+		step := tier.Step
+		if step == 0 {
+			step = interval
+		}
+		limit := int(tier.Retention/step) + 1
+		n := countKeys("load1", tier)
+		if n > limit {
+			t.Errorf("tier %s holds %d samples, want at most %d", tier.Name, n, limit)
+		}
+		total += n
+	}
+	if n := DB.Stat().KeysNum; n != total {
+		t.Errorf("database holds %d keys, want the %d samples", n, total)
+	}
+}
+
+func TestHistoryMerged(t *testing.T) {
+	tiers := []HistoryTier{
+		{Name: "raw", Retention: 100 * time.Millisecond},
+		{Name: "10ms", Step: 10 * time.Millisecond, Retention: 200 * time.Millisecond},
+	}
+	setupHistory(t, "@every 1s", tiers)
+
+	for i := 0; i < 2000; i++ {
+		if err := AddSample("load1", time.Now(), float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := FlushSamples(); err != nil {
+		t.Fatal(err)
+	}
+	written := DB.Stat().DiskSize
+
+	// Every sample expires, then the scheduled merge drops them from disk.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		size := DB.Stat().DiskSize
+		if size < written/10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("database still uses %d bytes of the %d written", size, written)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
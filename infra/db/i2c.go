@@ -0,0 +1,49 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// ErrI2CDeviceNotFound is returned when an I2C device name is not stored.
+var ErrI2CDeviceNotFound = errors.New("I2C device not found")
+
+type I2CDeviceMap map[string]dto.I2CDevice
+
+// GetI2CDevices returns every stored I2C device keyed by name.
+func GetI2CDevices() (I2CDeviceMap, error) {
+	devices := make(I2CDeviceMap)
+	jsonValue, err := DB.Get([]byte("i2c_devices"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return devices, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return devices, json.Unmarshal(jsonValue, &devices)
+}
+
+// SetI2CDevice inserts or replaces an I2C device.
+func SetI2CDevice(device dto.I2CDevice) error {
+	devices, err := GetI2CDevices()
+	if err != nil {
+		return err
+	}
+	devices[device.Name] = device
+	return SetJson("i2c_devices", devices)
+}
+
+// DeleteI2CDevice removes an I2C device by name.
+func DeleteI2CDevice(name string) error {
+	devices, err := GetI2CDevices()
+	if err != nil {
+		return err
+	}
+	if _, ok := devices[name]; !ok {
+		return ErrI2CDeviceNotFound
+	}
+	delete(devices, name)
+	return SetJson("i2c_devices", devices)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// ErrInterlockNotFound is returned when an interlock name is not stored.
+var ErrInterlockNotFound = errors.New("interlock not found")
+
+type InterlockMap map[string]dto.Interlock
+
+// GetInterlocks returns every stored interlock keyed by name.
+func GetInterlocks() (InterlockMap, error) {
+	interlocks := make(InterlockMap)
+	jsonValue, err := DB.Get([]byte("gpio_interlocks"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return interlocks, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return interlocks, json.Unmarshal(jsonValue, &interlocks)
+}
+
+// SetInterlock inserts or replaces an interlock.
+func SetInterlock(interlock dto.Interlock) error {
+	interlocks, err := GetInterlocks()
+	if err != nil {
+		return err
+	}
+	interlocks[interlock.Name] = interlock
+	return SetJson("gpio_interlocks", interlocks)
+}
+
+// DeleteInterlock removes an interlock by name.
+func DeleteInterlock(name string) error {
+	interlocks, err := GetInterlocks()
+	if err != nil {
+		return err
+	}
+	if _, ok := interlocks[name]; !ok {
+		return ErrInterlockNotFound
+	}
+	delete(interlocks, name)
+	return SetJson("gpio_interlocks", interlocks)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+var (
+	// ErrLabelNotFound is returned when a pin has no label.
+	ErrLabelNotFound = errors.New("label not found")
+	// ErrLabelExists is returned when a label is already given to another pin.
+	ErrLabelExists = errors.New("label already used by another pin")
+)
+
+type LabelMap map[string]dto.PinLabel // keyed by dto.Line
+
+// GetLabels returns every pin label keyed by line.
+func GetLabels() (LabelMap, error) {
+	labels := make(LabelMap)
+	jsonValue, err := DB.Get([]byte("pin_labels"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return labels, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonValue, &labels); err != nil {
+		return nil, err
+	}
+	// Labels stored before multiple chips were supported are keyed by offset only.
+	for key, label := range labels {
+		if label.Chip == "" {
+			label.Chip = configs.Conf.GPIOChip
+			delete(labels, key)
+			labels[label.Line().String()] = label
+		}
+	}
+	return labels, nil
+}
+
+// SetLabel labels a pin, labels being unique regardless of case.
+func SetLabel(label dto.PinLabel) error {
+	labels, err := GetLabels()
+	if err != nil {
+		return err
+	}
+	key := label.Line().String()
+	for k, l := range labels {
+		if k != key && strings.EqualFold(l.Label, label.Label) {
+			return ErrLabelExists
+		}
+	}
+	labels[key] = label
+	return SetJson("pin_labels", labels)
+}
+
+// DeleteLabel removes the label of a line.
+func DeleteLabel(line dto.Line) error {
+	labels, err := GetLabels()
+	if err != nil {
+		return err
+	}
+	if _, ok := labels[line.String()]; !ok {
+		return ErrLabelNotFound
+	}
+	delete(labels, line.String())
+	return SetJson("pin_labels", labels)
+}
+
+// FindLabel returns the pin labelled label, ignoring case.
+func FindLabel(label string) (dto.PinLabel, error) {
+	labels, err := GetLabels()
+	if err != nil {
+		return dto.PinLabel{}, err
+	}
+	for _, l := range labels {
+		if strings.EqualFold(l.Label, label) {
+			return l, nil
+		}
+	}
+	return dto.PinLabel{}, ErrLabelNotFound
+}
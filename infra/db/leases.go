@@ -0,0 +1,43 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+type LeaseMap map[string]dto.Lease // keyed by dto.Line
+
+// GetLeases returns every armed pin watchdog keyed by line.
+func GetLeases() (LeaseMap, error) {
+	leases := make(LeaseMap)
+	jsonValue, err := DB.Get([]byte("gpio_leases"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return leases, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return leases, json.Unmarshal(jsonValue, &leases)
+}
+
+// SetLease inserts or replaces the watchdog of a pin.
+func SetLease(lease dto.Lease) error {
+	leases, err := GetLeases()
+	if err != nil {
+		return err
+	}
+	leases[lease.Line().String()] = lease
+	return SetJson("gpio_leases", leases)
+}
+
+// DeleteLease removes the watchdog of a pin, if any.
+func DeleteLease(line dto.Line) error {
+	leases, err := GetLeases()
+	if err != nil {
+		return err
+	}
+	delete(leases, line.String())
+	return SetJson("gpio_leases", leases)
+}
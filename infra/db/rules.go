@@ -0,0 +1,77 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// ErrRuleNotFound is returned when a rule name is not stored.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// MaxRuleHistory is the number of rule firings kept, older ones are dropped.
+const MaxRuleHistory = 1000
+
+type RuleMap map[string]dto.Rule
+
+// GetRules returns every stored rule keyed by name.
+func GetRules() (RuleMap, error) {
+	rules := make(RuleMap)
+	jsonValue, err := DB.Get([]byte("rule_list"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return rules, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return rules, json.Unmarshal(jsonValue, &rules)
+}
+
+// SetRule inserts or replaces a rule.
+func SetRule(rule dto.Rule) error {
+	rules, err := GetRules()
+	if err != nil {
+		return err
+	}
+	rules[rule.Name] = rule
+	return SetJson("rule_list", rules)
+}
+
+// DeleteRule removes a rule by name.
+func DeleteRule(name string) error {
+	rules, err := GetRules()
+	if err != nil {
+		return err
+	}
+	if _, ok := rules[name]; !ok {
+		return ErrRuleNotFound
+	}
+	delete(rules, name)
+	return SetJson("rule_list", rules)
+}
+
+// GetRuleHistory returns the recorded rule firings, oldest first.
+func GetRuleHistory() ([]dto.RuleFiring, error) {
+	var history []dto.RuleFiring
+	jsonValue, err := DB.Get([]byte("rule_history"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return history, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return history, json.Unmarshal(jsonValue, &history)
+}
+
+// AddRuleFiring records a rule firing, keeping the last MaxRuleHistory ones.
+func AddRuleFiring(firing dto.RuleFiring) error {
+	history, err := GetRuleHistory()
+	if err != nil {
+		return err
+	}
+	history = append(history, firing)
+	if len(history) > MaxRuleHistory {
+		history = history[len(history)-MaxRuleHistory:]
+	}
+	return SetJson("rule_history", history)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// ErrScheduleNotFound is returned when a schedule name is not stored.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+type ScheduleMap map[string]dto.Schedule
+type ScheduleRunMap map[string]dto.ScheduleRun
+
+// GetSchedules returns every stored schedule keyed by name.
+func GetSchedules() (ScheduleMap, error) {
+	schedules := make(ScheduleMap)
+	jsonValue, err := DB.Get([]byte("schedule_list"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return schedules, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return schedules, json.Unmarshal(jsonValue, &schedules)
+}
+
+// SetSchedule inserts or replaces a schedule.
+func SetSchedule(schedule dto.Schedule) error {
+	schedules, err := GetSchedules()
+	if err != nil {
+		return err
+	}
+	schedules[schedule.Name] = schedule
+	return SetJson("schedule_list", schedules)
+}
+
+// DeleteSchedule removes a schedule and its runs by name.
+func DeleteSchedule(name string) error {
+	schedules, err := GetSchedules()
+	if err != nil {
+		return err
+	}
+	if _, ok := schedules[name]; !ok {
+		return ErrScheduleNotFound
+	}
+	delete(schedules, name)
+	if err := SetJson("schedule_list", schedules); err != nil {
+		return err
+	}
+
+	runs, err := GetScheduleRuns()
+	if err != nil {
+		return err
+	}
+	delete(runs, name)
+	return SetJson("schedule_runs", runs)
+}
+
+// GetScheduleRuns returns the runs of every schedule keyed by name.
+func GetScheduleRuns() (ScheduleRunMap, error) {
+	runs := make(ScheduleRunMap)
+	jsonValue, err := DB.Get([]byte("schedule_runs"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return runs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return runs, json.Unmarshal(jsonValue, &runs)
+}
+
+// SetScheduleRun stores the runs of a schedule.
+func SetScheduleRun(name string, run dto.ScheduleRun) error {
+	runs, err := GetScheduleRuns()
+	if err != nil {
+		return err
+	}
+	runs[name] = run
+	return SetJson("schedule_runs", runs)
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// ErrTokenNotFound is returned when a token name is not stored.
+var ErrTokenNotFound = errors.New("token not found")
+
+// TokenRecord is an API token as stored, with the hash of its secret.
+type TokenRecord struct {
+	dto.Token
+	Hash string `json:"hash"`
+}
+
+type TokenMap map[string]TokenRecord
+
+// GetTokens returns every stored token keyed by name.
+func GetTokens() (TokenMap, error) {
+	tokens := make(TokenMap)
+	jsonValue, err := DB.Get([]byte("token_list"))
+	if errors.Is(err, rosedb.ErrKeyNotFound) {
+		return tokens, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return tokens, json.Unmarshal(jsonValue, &tokens)
+}
+
+// SetToken inserts or replaces a token.
+func SetToken(token TokenRecord) error {
+	tokens, err := GetTokens()
+	if err != nil {
+		return err
+	}
+	tokens[token.Name] = token
+	return SetJson("token_list", tokens)
+}
+
+// DeleteToken removes a token by name.
+func DeleteToken(name string) error {
+	tokens, err := GetTokens()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[name]; !ok {
+		return ErrTokenNotFound
+	}
+	delete(tokens, name)
+	return SetJson("token_list", tokens)
+}
@@ -0,0 +1,44 @@
+package gpio
+
+import (
+	"log"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// heldMode returns a copy of the configuration held on line, nil if the line
+// is not held.
+// The caller must hold mu.
+func heldMode(line dto.Line) *dto.PinMode {
+	if mode, held := modes[line]; held {
+		return &mode
+	}
+	return nil
+}
+
+// audit records a change of pin in the audit log, old being the
+// configuration held before the change.
+// The caller must hold mu.
+func audit(action string, old *dto.PinMode, pin dto.PinMode, source dto.Source, detail string) {
+	change := dto.PinChange{
+		Time:   time.Now(),
+		Chip:   pin.Chip,
+		Pin:    pin.Pin,
+		Action: action,
+		Old:    old,
+		New:    pin,
+		Detail: detail,
+		Source: source,
+	}
+	if err := db.AddPinChange(change); err != nil {
+		log.Printf("GPIO: Error recording change of pin %s: %v", pin.Line(), err)
+	}
+}
+
+// GetHistory returns the entries of the audit log selected by q, newest
+// first, and the cursor of the next page.
+func GetHistory(q dto.AuditQuery) ([]dto.PinChange, string, error) {
+	return db.GetPinChanges(q)
+}
@@ -0,0 +1,280 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// ErrInvalidBatch is returned when the pins of a batch cannot be requested together.
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// lineBatch is a set of output lines requested together, so that their values
+// change in a single ioctl.
+type lineBatch struct {
+	lines   Lines
+	members []dto.Line // in the order of the request offsets
+
+	mu sync.Mutex // serialises read-modify-write of the values of single members
+}
+
+// batchLine is a member of a lineBatch.
+type batchLine struct {
+	batch *lineBatch
+	index int
+}
+
+// Value returns the value of the member.
+func (l *batchLine) Value() (int, error) {
+	values := make([]int, len(l.batch.members))
+	if err := l.batch.lines.Values(values); err != nil {
+		return 0, err
+	}
+	return values[l.index], nil
+}
+
+// SetValue sets the value of the member, leaving the others untouched.
+func (l *batchLine) SetValue(value int) error {
+	b := l.batch
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	values := make([]int, len(b.members))
+	if err := b.lines.Values(values); err != nil {
+		return err
+	}
+	values[l.index] = value
+	return b.lines.SetValues(values)
+}
+
+// setValues sets the values of all members at once.
+func (b *lineBatch) setValues(values []int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lines.SetValues(values)
+}
+
+// SetBatch configures several output pins of the same chip as a single line
+// request and sets their values at once. If a line cannot be acquired the
+// pins are returned to their previous configuration and nothing is stored.
+// Setting new values on the exact set of pins of a held batch with the same
+// configuration changes them without requesting the lines again.
+func SetBatch(pins []dto.PinMode, source dto.Source) error {
+	if !CheckChip() {
+		return errors.New("GPIO chip not initialized")
+	}
+	if len(pins) == 0 {
+		return fmt.Errorf("%w: no pins", ErrInvalidBatch)
+	}
+
+	chip := DefaultLine(0).Chip
+	seen := make(map[dto.Line]bool, len(pins))
+	for i := range pins {
+		if pins[i].Chip == "" {
+			pins[i].Chip = chip
+		}
+		if pins[i].Direction != dto.Output {
+			return fmt.Errorf("%w: pin %s is not an output", ErrInvalidBatch, pins[i].Line())
+		}
+		if pins[i].Chip != pins[0].Chip {
+			return fmt.Errorf("%w: all pins must belong to the same chip", ErrInvalidBatch)
+		}
+		if seen[pins[i].Line()] {
+			return fmt.Errorf("%w: pin %s is repeated", ErrInvalidBatch, pins[i].Line())
+		}
+		seen[pins[i].Line()] = true
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	off, err := checkInterlocks(pins)
+	if err != nil {
+		return err
+	}
+	old := make([]*dto.PinMode, len(pins))
+	for i, pin := range pins {
+		old[i] = heldMode(pin.Line())
+	}
+	if b := heldBatch(pins); b != nil {
+		if err := updateBatch(b, pins); err != nil {
+			return err
+		}
+	} else if err := requestBatch(pins); err != nil {
+		return err
+	}
+	markInactive(off)
+	for i, pin := range pins {
+		audit(dto.ChangeBatch, old[i], pin, source, "")
+	}
+
+	if err := db.SetPins(pins); err != nil {
+		return fmt.Errorf("GPIO: Error setting pin values in database: %w", err)
+	}
+	log.Printf("GPIO: Batch of %d pins on %s set", len(pins), pins[0].Chip)
+	return nil
+}
+
+// heldBatch returns the batch holding exactly the lines of pins with the same
+// configuration, nil if there is none.
+// The caller must hold mu.
+func heldBatch(pins []dto.PinMode) *lineBatch {
+	var b *lineBatch
+	for _, pin := range pins {
+		l, ok := lines[pin.Line()].(*batchLine)
+		if !ok || (b != nil && l.batch != b) {
+			return nil
+		}
+		b = l.batch
+		prev := modes[pin.Line()]
+		if prev.Active != pin.Active || prev.Bias != pin.Bias || prev.Drive != pin.Drive {
+			return nil
+		}
+		if _, pulsing := pulses[pin.Line()]; pulsing {
+			return nil
+		}
+	}
+	if len(b.members) != len(pins) {
+		return nil
+	}
+	return b
+}
+
+// updateBatch sets new values on a held batch.
+// The caller must hold mu.
+func updateBatch(b *lineBatch, pins []dto.PinMode) error {
+	values := make([]int, len(b.members))
+	for _, pin := range pins {
+		values[lines[pin.Line()].(*batchLine).index] = pin.Value
+	}
+	if err := b.setValues(values); err != nil {
+		return fmt.Errorf("GPIO: Error setting batch values: %w", err)
+	}
+	for _, pin := range pins {
+		modes[pin.Line()] = pin
+	}
+	return nil
+}
+
+// requestBatch releases the lines of pins and requests them as a new batch.
+// The lines are checked before anything is released, and if the request still
+// fails the previous configuration is restored, watched pins keeping their
+// subscribers.
+// The caller must hold mu.
+func requestBatch(pins []dto.PinMode) error {
+	c, err := getChip(pins[0].Chip)
+	if err != nil {
+		return err
+	}
+	if err := checkBatchLines(c, pins); err != nil {
+		return err
+	}
+
+	offsets := make([]int, len(pins))
+	cfgs := make([]LineConfig, len(pins))
+	var prev []dto.PinMode
+	for i, pin := range pins {
+		offsets[i] = pin.Pin
+		cfgs[i] = lineConfig(pin, true)
+		if mode, held := modes[pin.Line()]; held {
+			prev = append(prev, mode)
+		}
+	}
+
+	// Watches are detached before the lines are released, so that their
+	// subscribers are only closed once the batch is held.
+	detached := make(map[dto.Line]*watch)
+	for _, pin := range pins {
+		if w, ok := watches[pin.Line()]; ok {
+			detached[pin.Line()] = w
+			delete(watches, pin.Line())
+		}
+		releaseLine(pin.Line())
+	}
+
+	ll, err := c.RequestLines(offsets, cfgs)
+	if err != nil {
+		for _, mode := range prev {
+			if err := restoreMode(mode, detached[mode.Line()]); err != nil {
+				log.Printf("GPIO: Error restoring pin %s after failed batch: %v", mode.Line(), err)
+			}
+		}
+		return fmt.Errorf("GPIO: Error requesting lines for batch: %w", err)
+	}
+	for _, w := range detached {
+		w.closeSubscribers()
+	}
+
+	b := &lineBatch{lines: ll, members: make([]dto.Line, len(pins))}
+	for i, pin := range pins {
+		b.members[i] = pin.Line()
+		lines[pin.Line()] = &batchLine{batch: b, index: i}
+		modes[pin.Line()] = pin
+	}
+	return nil
+}
+
+// checkBatchLines fails if a line of pins does not exist or is used by
+// another consumer, which would make the batch request fail.
+// The caller must hold mu.
+func checkBatchLines(c Chip, pins []dto.PinMode) error {
+	for _, pin := range pins {
+		if pin.Pin < 0 || pin.Pin >= c.Lines() {
+			return fmt.Errorf("%w: pin %s does not exist", ErrInvalidBatch, pin.Line())
+		}
+		if _, held := lines[pin.Line()]; held {
+			continue
+		}
+		info, err := c.LineInfo(pin.Pin)
+		if err != nil {
+			return fmt.Errorf("GPIO: Error reading line info for pin %s: %w", pin.Line(), err)
+		}
+		if info.Used {
+			return fmt.Errorf("%w: pin %s is used by %q", ErrInvalidBatch, pin.Line(), info.Consumer)
+		}
+	}
+	return nil
+}
+
+// restoreMode requests the line of mode again after a failed batch, with the
+// edge detection of w if the pin was watched.
+// The caller must hold mu.
+func restoreMode(mode dto.PinMode, w *watch) error {
+	if w == nil {
+		return applyMode(mode)
+	}
+	if _, err := requestLine(mode, false, &LineWatch{Debounce: w.debounce, Handler: w.handle}); err != nil {
+		w.closeSubscribers()
+		return err
+	}
+	watches[mode.Line()] = w
+	return nil
+}
+
+// splitBatch closes the batch request of a released line and requests the
+// other members of the batch again on their own, at their configured value.
+// The caller must hold mu.
+func splitBatch(b *lineBatch, released dto.Line) {
+	var others []dto.PinMode
+	for _, member := range b.members {
+		l, ok := lines[member].(*batchLine)
+		if member == released || !ok || l.batch != b {
+			continue
+		}
+		cancelPulse(member)
+		others = append(others, modes[member])
+		delete(lines, member)
+	}
+	_ = b.lines.Close()
+
+	for _, mode := range others {
+		if _, err := requestLine(mode, true, nil); err != nil {
+			log.Println(err)
+			delete(modes, mode.Line())
+		}
+	}
+}
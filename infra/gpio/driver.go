@@ -0,0 +1,129 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// Driver names accepted in GPIO_DRIVER.
+const (
+	DriverCdev    = "gpiocdev" // the GPIO character devices of the kernel
+	DriverSim     = "sim"      // an in-memory chip, for machines without GPIO lines
+	DriverGPIOSim = "gpio-sim" // a chip created with the gpio-sim kernel module
+)
+
+// ErrNotSimulated is returned when driving the input of a chip that is not simulated.
+var ErrNotSimulated = errors.New("GPIO chip is not simulated")
+
+// Driver is a GPIO backend.
+type Driver interface {
+	// Chips returns the names of the chips of the backend, e.g. gpiochip0.
+	Chips() []string
+	// OpenChip opens a chip whose lines are requested as consumer.
+	OpenChip(name, consumer string) (Chip, error)
+}
+
+// Chip is an open GPIO chip.
+type Chip interface {
+	Name() string
+	Label() string
+	Lines() int
+	LineInfo(offset int) (gpiocdev.LineInfo, error)
+	// RequestLine requests a single line, with edge detection on both edges
+	// if watch is not nil.
+	RequestLine(offset int, cfg LineConfig, watch *LineWatch) (Line, error)
+	// RequestLines requests several output lines at once, cfgs being in the
+	// order of offsets.
+	RequestLines(offsets []int, cfgs []LineConfig) (Lines, error)
+	Close() error
+}
+
+// Line is a requested line.
+type Line interface {
+	Value() (int, error)
+	SetValue(value int) error
+	Close() error
+}
+
+// Lines is a set of lines requested together, their values being in the
+// order of the requested offsets.
+type Lines interface {
+	Values(values []int) error
+	SetValues(values []int) error
+	Close() error
+}
+
+// LineConfig is the configuration a line is requested with.
+type LineConfig struct {
+	Output    bool
+	Value     int // initial value of an output
+	ActiveLow bool
+	Bias      string // dto.PullUp, dto.PullDown, dto.BiasDisabled or empty to leave it as is
+	Drive     string // dto.OpenDrain, dto.OpenSource or empty for push-pull, outputs only
+}
+
+// LineWatch is the edge detection of an input line.
+type LineWatch struct {
+	Debounce time.Duration
+	Handler  func(gpiocdev.LineEvent)
+}
+
+// InputSimulator is implemented by the chips of simulated drivers, whose
+// input levels are driven from software.
+type InputSimulator interface {
+	// SetInput pulls a line to the physical level value, as an external
+	// device would.
+	SetInput(offset, value int) error
+}
+
+// newDriver returns the driver selected in the configuration.
+func newDriver(cfg *configs.Cfg) (Driver, error) {
+	switch cfg.GPIODriver {
+	case "", DriverCdev:
+		return cdevDriver{}, nil
+	case DriverSim:
+		return newSimDriver(cfg.GPIOChip, cfg.GPIOSimLines, cfg.GPIOSimNames), nil
+	case DriverGPIOSim:
+		return newGPIOSimDriver(cfg.AppName, cfg.GPIOSimLines, cfg.GPIOSimNames)
+	default:
+		return nil, fmt.Errorf("unknown GPIO_DRIVER %q, use %s, %s or %s", cfg.GPIODriver, DriverCdev, DriverSim, DriverGPIOSim)
+	}
+}
+
+// simLineName returns the name of line offset of a simulated chip: the
+// configured one, or GPIO<offset> like on the Raspberry Pi.
+func simLineName(names []string, offset int) string {
+	if offset < len(names) {
+		return names[offset]
+	}
+	return fmt.Sprintf("GPIO%d", offset)
+}
+
+// SimulateInput pulls a pin of a simulated chip to the physical level value,
+// as if an external device drove it. Edges are reported to the subscribers of
+// the pin like on hardware.
+func SimulateInput(line dto.Line, value int) error {
+	if value != 0 && value != 1 {
+		return errors.New("invalid value, use 0 or 1")
+	}
+
+	mu.RLock()
+	c, err := getChip(line.Chip)
+	mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	sim, ok := c.(InputSimulator)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotSimulated, line.Chip)
+	}
+	if line.Offset < 0 || line.Offset >= c.Lines() {
+		return fmt.Errorf("%w: %s", ErrUnknownPin, line)
+	}
+	return sim.SetInput(line.Offset, value)
+}
@@ -0,0 +1,113 @@
+package gpio
+
+import (
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// cdevDriver drives the GPIO character devices of the kernel.
+type cdevDriver struct{}
+
+func (cdevDriver) Chips() []string {
+	return gpiocdev.Chips()
+}
+
+func (cdevDriver) OpenChip(name, consumer string) (Chip, error) {
+	c, err := gpiocdev.NewChip(name, gpiocdev.WithConsumer(consumer))
+	if err != nil {
+		return nil, err
+	}
+	return &cdevChip{c}, nil
+}
+
+// cdevChip is a chip opened through its character device.
+type cdevChip struct {
+	*gpiocdev.Chip
+}
+
+func (c *cdevChip) Name() string {
+	return c.Chip.Name
+}
+
+func (c *cdevChip) Label() string {
+	return c.Chip.Label
+}
+
+func (c *cdevChip) RequestLine(offset int, cfg LineConfig, watch *LineWatch) (Line, error) {
+	var options []gpiocdev.LineReqOption
+	if cfg.Output {
+		options = append(options, gpiocdev.AsOutput(cfg.Value))
+	} else {
+		options = append(options, gpiocdev.AsInput)
+	}
+	for _, option := range cdevLineConfig(cfg) {
+		options = append(options, option)
+	}
+
+	if watch != nil {
+		options = append(options, gpiocdev.WithBothEdges, gpiocdev.WithEventHandler(watch.Handler))
+		if watch.Debounce > 0 {
+			options = append(options, gpiocdev.WithDebounce(watch.Debounce))
+		}
+	}
+	l, err := c.Chip.RequestLine(offset, options...)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (c *cdevChip) RequestLines(offsets []int, cfgs []LineConfig) (Lines, error) {
+	values := make([]int, len(cfgs))
+	options := []gpiocdev.LineReqOption{nil} // replaced by the output values below
+	for i, cfg := range cfgs {
+		values[i] = cfg.Value
+		var subset []gpiocdev.SubsetLineConfigOption
+		for _, option := range cdevLineConfig(cfg) {
+			subset = append(subset, option)
+		}
+		if len(subset) > 0 {
+			options = append(options, gpiocdev.WithLines([]int{offsets[i]}, subset...))
+		}
+	}
+	options[0] = gpiocdev.AsOutput(values...)
+	ll, err := c.Chip.RequestLines(offsets, options...)
+	if err != nil {
+		return nil, err
+	}
+	return ll, nil
+}
+
+// cdevLineConfigOption is an option that applies to a single line request as
+// well as to a subset of the lines of a batch request.
+type cdevLineConfigOption interface {
+	gpiocdev.LineReqOption
+	gpiocdev.SubsetLineConfigOption
+}
+
+// cdevLineConfig translates the active level, bias and drive of cfg into options.
+func cdevLineConfig(cfg LineConfig) []cdevLineConfigOption {
+	var options []cdevLineConfigOption
+	if cfg.ActiveLow {
+		options = append(options, gpiocdev.AsActiveLow)
+	}
+
+	switch cfg.Bias {
+	case dto.PullUp:
+		options = append(options, gpiocdev.WithPullUp)
+	case dto.PullDown:
+		options = append(options, gpiocdev.WithPullDown)
+	case dto.BiasDisabled:
+		options = append(options, gpiocdev.WithBiasDisabled)
+	}
+
+	if cfg.Output {
+		switch cfg.Drive {
+		case dto.OpenDrain:
+			options = append(options, gpiocdev.AsOpenDrain)
+		case dto.OpenSource:
+			options = append(options, gpiocdev.AsOpenSource)
+		}
+	}
+	return options
+}
@@ -0,0 +1,103 @@
+package gpio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gpioSimRoot is the configfs directory of the gpio-sim kernel module.
+const gpioSimRoot = "/sys/kernel/config/gpio-sim"
+
+// gpioSimDriver drives a chip created with the gpio-sim kernel module. Its
+// lines are requested through the character device like on hardware and its
+// inputs are pulled through sysfs.
+type gpioSimDriver struct {
+	chip  string // name given by the kernel, e.g. gpiochip3
+	sysfs string // sysfs directory of the simulated chip
+}
+
+// newGPIOSimDriver creates the simulated chip, or reuses the one created by a
+// previous run. It requires the gpio-sim module and configfs to be mounted.
+func newGPIOSimDriver(name string, lines int, names []string) (*gpioSimDriver, error) {
+	dev := filepath.Join(gpioSimRoot, strings.ToLower(strings.ReplaceAll(name, " ", "-")))
+	bank := filepath.Join(dev, "bank0")
+
+	if live, err := os.ReadFile(filepath.Join(dev, "live")); err != nil || strings.TrimSpace(string(live)) != "1" {
+		if err := createGPIOSim(dev, bank, lines, names); err != nil {
+			return nil, fmt.Errorf("error creating gpio-sim chip (is the gpio-sim module loaded?): %w", err)
+		}
+	}
+
+	devName, err := os.ReadFile(filepath.Join(dev, "dev_name"))
+	if err != nil {
+		return nil, err
+	}
+	chipName, err := os.ReadFile(filepath.Join(bank, "chip_name"))
+	if err != nil {
+		return nil, err
+	}
+	chip := strings.TrimSpace(string(chipName))
+	return &gpioSimDriver{
+		chip:  chip,
+		sysfs: filepath.Join("/sys/devices/platform", strings.TrimSpace(string(devName)), chip),
+	}, nil
+}
+
+// createGPIOSim configures a simulated chip with a single bank of lines and
+// brings it up.
+func createGPIOSim(dev, bank string, lines int, names []string) error {
+	if err := os.MkdirAll(bank, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(bank, "num_lines"), []byte(strconv.Itoa(lines)), 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(bank, "label"), []byte("raspc-sim"), 0o644); err != nil {
+		return err
+	}
+	for offset := 0; offset < lines; offset++ {
+		line := filepath.Join(bank, "line"+strconv.Itoa(offset))
+		if err := os.MkdirAll(line, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(line, "name"), []byte(simLineName(names, offset)), 0o644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(dev, "live"), []byte("1"), 0o644)
+}
+
+func (d *gpioSimDriver) Chips() []string {
+	return []string{d.chip}
+}
+
+func (d *gpioSimDriver) OpenChip(name, consumer string) (Chip, error) {
+	if name != d.chip {
+		return nil, fmt.Errorf("unknown simulated chip %q", name)
+	}
+	c, err := cdevDriver{}.OpenChip(name, consumer)
+	if err != nil {
+		return nil, err
+	}
+	return &gpioSimChip{cdevChip: c.(*cdevChip), sysfs: d.sysfs}, nil
+}
+
+// gpioSimChip is a gpio-sim chip opened through its character device.
+type gpioSimChip struct {
+	*cdevChip
+	sysfs string
+}
+
+// SetInput pulls a line to value through the sysfs attribute of gpio-sim.
+// The kernel reports the resulting edge to the requested line.
+func (c *gpioSimChip) SetInput(offset, value int) error {
+	pull := "pull-down"
+	if value == 1 {
+		pull = "pull-up"
+	}
+	attr := filepath.Join(c.sysfs, "sim_gpio"+strconv.Itoa(offset), "pull")
+	return os.WriteFile(attr, []byte(pull), 0o644)
+}
@@ -0,0 +1,314 @@
+package gpio
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// simDriver is an in-memory GPIO backend with a single chip, so that the API
+// can be used on machines without GPIO lines.
+type simDriver struct {
+	chip *simChip
+}
+
+func newSimDriver(name string, lines int, names []string) *simDriver {
+	c := &simChip{name: name, label: "raspc-sim", lines: make([]simLine, lines)}
+	for offset := range c.lines {
+		c.lines[offset].name = simLineName(names, offset)
+	}
+	return &simDriver{chip: c}
+}
+
+func (d *simDriver) Chips() []string {
+	return []string{d.chip.name}
+}
+
+func (d *simDriver) OpenChip(name, consumer string) (Chip, error) {
+	if name != d.chip.name {
+		return nil, fmt.Errorf("unknown simulated chip %q", name)
+	}
+	d.chip.mu.Lock()
+	d.chip.consumer = consumer
+	d.chip.mu.Unlock()
+	return d.chip, nil
+}
+
+// simChip is a simulated chip. Outputs keep the value written to them and
+// inputs read the level they are pulled to by SetInput, or by their bias.
+type simChip struct {
+	name, label string
+
+	mu       sync.Mutex // guards consumer and lines
+	consumer string
+	lines    []simLine
+}
+
+// simLine is the state of a line of a simChip.
+type simLine struct {
+	name   string
+	owner  *simRequest // nil while the line is free
+	cfg    LineConfig
+	value  int // logical value of an output
+	pulled *int
+	watch  *LineWatch
+	seqno  uint32
+}
+
+// level returns the physical level of the line.
+// The caller must hold c.mu.
+func (l *simLine) level() int {
+	if l.owner != nil && l.cfg.Output {
+		return l.value ^ activeLowBit(l.cfg)
+	}
+	if l.pulled != nil {
+		return *l.pulled
+	}
+	if l.owner != nil && l.cfg.Bias == dto.PullUp {
+		return 1
+	}
+	return 0
+}
+
+// logical returns the value of the line as read by its consumer.
+// The caller must hold c.mu.
+func (l *simLine) logical() int {
+	if l.owner != nil && l.cfg.Output {
+		return l.value
+	}
+	return l.level() ^ activeLowBit(l.cfg)
+}
+
+func activeLowBit(cfg LineConfig) int {
+	if cfg.ActiveLow {
+		return 1
+	}
+	return 0
+}
+
+func (c *simChip) Name() string  { return c.name }
+func (c *simChip) Label() string { return c.label }
+func (c *simChip) Lines() int    { return len(c.lines) }
+func (c *simChip) Close() error  { return nil }
+
+func (c *simChip) LineInfo(offset int) (gpiocdev.LineInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if offset < 0 || offset >= len(c.lines) {
+		return gpiocdev.LineInfo{}, gpiocdev.ErrInvalidOffset
+	}
+	l := &c.lines[offset]
+	info := gpiocdev.LineInfo{
+		Offset: offset,
+		Name:   l.name,
+		Config: gpiocdev.LineConfig{Direction: gpiocdev.LineDirectionInput},
+	}
+	if l.owner == nil {
+		return info, nil
+	}
+
+	info.Used = true
+	info.Consumer = c.consumer
+	info.Config.ActiveLow = l.cfg.ActiveLow
+	if l.cfg.Output {
+		info.Config.Direction = gpiocdev.LineDirectionOutput
+		switch l.cfg.Drive {
+		case dto.OpenDrain:
+			info.Config.Drive = gpiocdev.LineDriveOpenDrain
+		case dto.OpenSource:
+			info.Config.Drive = gpiocdev.LineDriveOpenSource
+		}
+	}
+	switch l.cfg.Bias {
+	case dto.PullUp:
+		info.Config.Bias = gpiocdev.LineBiasPullUp
+	case dto.PullDown:
+		info.Config.Bias = gpiocdev.LineBiasPullDown
+	case dto.BiasDisabled:
+		info.Config.Bias = gpiocdev.LineBiasDisabled
+	}
+	if l.watch != nil {
+		info.Config.EdgeDetection = gpiocdev.LineEdgeBoth
+		info.Config.Debounced = l.watch.Debounce > 0
+		info.Config.DebouncePeriod = l.watch.Debounce
+	}
+	return info, nil
+}
+
+func (c *simChip) RequestLine(offset int, cfg LineConfig, watch *LineWatch) (Line, error) {
+	r, err := c.request([]int{offset}, []LineConfig{cfg})
+	if err != nil {
+		return nil, err
+	}
+	if watch != nil && !cfg.Output {
+		c.mu.Lock()
+		c.lines[offset].watch = watch
+		c.mu.Unlock()
+	}
+	return &simLineRequest{r}, nil
+}
+
+func (c *simChip) RequestLines(offsets []int, cfgs []LineConfig) (Lines, error) {
+	return c.request(offsets, cfgs)
+}
+
+// request takes the lines at offsets, failing like the kernel if one of them
+// is out of range or already requested.
+func (c *simChip) request(offsets []int, cfgs []LineConfig) (*simRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, offset := range offsets {
+		if offset < 0 || offset >= len(c.lines) {
+			return nil, gpiocdev.ErrInvalidOffset
+		}
+		if c.lines[offset].owner != nil {
+			return nil, syscall.EBUSY
+		}
+	}
+
+	r := &simRequest{chip: c, offsets: append([]int(nil), offsets...)}
+	for i, offset := range offsets {
+		l := &c.lines[offset]
+		l.owner = r
+		l.cfg = cfgs[i]
+		l.value = cfgs[i].Value
+		l.watch = nil
+	}
+	return r, nil
+}
+
+// SetInput pulls a line to the physical level value and reports the edge to
+// the watch of the line, if any. Debouncing is not simulated.
+func (c *simChip) SetInput(offset, value int) error {
+	c.mu.Lock()
+	if offset < 0 || offset >= len(c.lines) {
+		c.mu.Unlock()
+		return gpiocdev.ErrInvalidOffset
+	}
+	l := &c.lines[offset]
+	if l.owner != nil && l.cfg.Output {
+		c.mu.Unlock()
+		return fmt.Errorf("line %s:%d is an output", c.name, offset)
+	}
+	before := l.logical()
+	l.pulled = &value
+	after := l.logical()
+
+	watch := l.watch
+	var ev gpiocdev.LineEvent
+	if watch != nil && before != after {
+		l.seqno++
+		ev = gpiocdev.LineEvent{
+			Offset:    offset,
+			Timestamp: time.Duration(time.Now().UnixNano()),
+			Type:      gpiocdev.LineEventFallingEdge,
+			Seqno:     l.seqno,
+			LineSeqno: l.seqno,
+		}
+		if after == 1 {
+			ev.Type = gpiocdev.LineEventRisingEdge
+		}
+	}
+	c.mu.Unlock()
+
+	// The handler runs outside of c.mu, as it does on the event goroutine of gpiocdev.
+	if watch != nil && before != after {
+		watch.Handler(ev)
+	}
+	return nil
+}
+
+// simRequest is a set of lines of a simChip requested together.
+type simRequest struct {
+	chip    *simChip
+	offsets []int
+}
+
+// owned returns the state of the requested lines, failing once the request is closed.
+// The caller must hold r.chip.mu.
+func (r *simRequest) owned() ([]*simLine, error) {
+	lines := make([]*simLine, len(r.offsets))
+	for i, offset := range r.offsets {
+		l := &r.chip.lines[offset]
+		if l.owner != r {
+			return nil, gpiocdev.ErrClosed
+		}
+		lines[i] = l
+	}
+	return lines, nil
+}
+
+func (r *simRequest) Values(values []int) error {
+	r.chip.mu.Lock()
+	defer r.chip.mu.Unlock()
+
+	lines, err := r.owned()
+	if err != nil {
+		return err
+	}
+	for i, l := range lines {
+		if i < len(values) {
+			values[i] = l.logical()
+		}
+	}
+	return nil
+}
+
+func (r *simRequest) SetValues(values []int) error {
+	r.chip.mu.Lock()
+	defer r.chip.mu.Unlock()
+
+	lines, err := r.owned()
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if !l.cfg.Output {
+			return gpiocdev.ErrPermissionDenied
+		}
+	}
+	for i, l := range lines {
+		if i < len(values) {
+			l.value = values[i]
+		}
+	}
+	return nil
+}
+
+func (r *simRequest) Close() error {
+	r.chip.mu.Lock()
+	defer r.chip.mu.Unlock()
+
+	lines, err := r.owned()
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		l.owner = nil
+		l.watch = nil
+	}
+	return nil
+}
+
+// simLineRequest is a single requested line of a simChip.
+type simLineRequest struct {
+	*simRequest
+}
+
+func (l *simLineRequest) Value() (int, error) {
+	values := make([]int, 1)
+	if err := l.Values(values); err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+func (l *simLineRequest) SetValue(value int) error {
+	return l.SetValues([]int{value})
+}
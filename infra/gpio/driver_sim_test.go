@@ -0,0 +1,478 @@
+package gpio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+const testChip = "gpiochip0"
+
+var testSource = dto.Source{Type: dto.SourceAPI, Token: "test"}
+
+// setupSim opens a fresh database and a simulated chip of 32 lines as the
+// only chip, releasing every line at the end of the test.
+func setupSim(t *testing.T) *simChip {
+	t.Helper()
+	configs.Conf = &configs.Cfg{AppName: "raspc-test", DBDir: t.TempDir(), GPIOChip: testChip}
+	if err := db.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.DB.Close() })
+
+	c, err := newSimDriver(testChip, 32, nil).OpenChip(testChip, configs.Conf.AppName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	chips = map[string]Chip{testChip: c}
+	defaultChip = testChip
+	lines = make(map[dto.Line]lineHandle)
+	modes = make(map[dto.Line]dto.PinMode)
+	lineNames = make(map[string][]dto.Line)
+	watches = make(map[dto.Line]*watch)
+	pulses = make(map[dto.Line]*pulse)
+	pwms = make(map[dto.Line]*pwm)
+	leases = make(map[dto.Line]*lease)
+	interlocks = make(map[string]dto.Interlock)
+	lastInactive = make(map[dto.Line]time.Time)
+	indexLineNames(testChip, c)
+
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for line := range modes {
+			releaseLine(line)
+		}
+	})
+	return c.(*simChip)
+}
+
+func line(offset int) dto.Line {
+	return dto.Line{Chip: testChip, Offset: offset}
+}
+
+func output(offset, value int) dto.PinMode {
+	return dto.PinMode{Chip: testChip, Pin: offset, Direction: dto.Output, Value: value}
+}
+
+// level returns the physical level of a line of the simulated chip.
+func level(c *simChip, offset int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lines[offset].level()
+}
+
+func held(offset int) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := lines[line(offset)]
+	return ok
+}
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return Event{}
+}
+
+func expectNoEvent(t *testing.T, ch <-chan Event) {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if ok {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+		t.Fatal("subscription closed")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func expectClosed(t *testing.T, ch <-chan Event) {
+	t.Helper()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("subscription still open")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription still open")
+	}
+}
+
+func TestSetBool(t *testing.T) {
+	c := setupSim(t)
+
+	if err := SetBool(dto.PinMode{Pin: 5, Direction: dto.Output, Value: 1}, testSource); err != nil {
+		t.Fatal(err)
+	}
+	if got := level(c, 5); got != 1 {
+		t.Errorf("level of pin 5 = %d, want 1", got)
+	}
+	active := dto.PinMode{Pin: 6, Direction: dto.Output, Value: 1, Active: dto.Low}
+	if err := SetBool(active, testSource); err != nil {
+		t.Fatal(err)
+	}
+	if got := level(c, 6); got != 0 {
+		t.Errorf("level of active low pin 6 = %d, want 0", got)
+	}
+	if err := SetBool(dto.PinMode{Pin: 7, Direction: dto.Input, Bias: dto.PullUp}, testSource); err != nil {
+		t.Fatal(err)
+	}
+
+	for offset, want := range map[int]int{5: 1, 6: 1, 7: 1} {
+		state, err := GetPin(line(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !state.Held || state.Value == nil || *state.Value != want {
+			t.Errorf("pin %d: held %v, value %v, want %d", offset, state.Held, state.Value, want)
+		}
+		if state.Configured == nil || state.Configured.Pin != offset || state.Configured.Chip != testChip {
+			t.Errorf("pin %d: configured %+v", offset, state.Configured)
+		}
+	}
+
+	// Reconfiguring a pin replaces its line request.
+	if err := SetBool(dto.PinMode{Pin: 5, Direction: dto.Output, Value: 0}, testSource); err != nil {
+		t.Fatal(err)
+	}
+	if got := level(c, 5); got != 0 {
+		t.Errorf("level of pin 5 = %d, want 0", got)
+	}
+	if err := SetBool(dto.PinMode{Pin: 40, Direction: dto.Output}, testSource); err == nil {
+		t.Error("setting a pin out of the chip succeeded")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	setupSim(t)
+	pin := line(17)
+
+	both, cancelBoth, err := Subscribe(pin, dto.EventSubscription{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rising, cancelRising, err := Subscribe(pin, dto.EventSubscription{Edge: dto.EdgeRising})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Subscribe(pin, dto.EventSubscription{Debounce: "10ms"}); err == nil {
+		t.Error("subscribing with another debounce period succeeded")
+	}
+
+	if err := SimulateInput(pin, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []<-chan Event{both, rising} {
+		if ev := receive(t, ch); ev.Edge != dto.EdgeRising || ev.Value != 1 || ev.Pin != 17 || ev.Chip != testChip {
+			t.Errorf("event = %+v, want a rising edge on pin 17", ev)
+		}
+	}
+	// Pulling the line to the level it is at is not an edge.
+	if err := SimulateInput(pin, 1); err != nil {
+		t.Fatal(err)
+	}
+	expectNoEvent(t, both)
+
+	if err := SimulateInput(pin, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ev := receive(t, both); ev.Edge != dto.EdgeFalling || ev.Value != 0 {
+		t.Errorf("event = %+v, want a falling edge", ev)
+	}
+	expectNoEvent(t, rising)
+
+	cancelRising()
+	expectClosed(t, rising)
+	if !held(17) {
+		t.Fatal("line released while a subscriber is left")
+	}
+	cancelBoth()
+	expectClosed(t, both)
+	if held(17) {
+		t.Error("line still held after the last subscriber left")
+	}
+
+	// Outputs cannot be watched, and reconfiguring a watched pin ends the subscription.
+	if err := SetBool(output(5, 1), testSource); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Subscribe(line(5), dto.EventSubscription{}); err == nil {
+		t.Error("subscribing to an output succeeded")
+	}
+	ch, _, err := Subscribe(pin, dto.EventSubscription{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBool(output(17, 0), testSource); err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, ch)
+}
+
+// failingChip fails every batch request, as the kernel does when a line is
+// taken by another program between the checks and the request.
+type failingChip struct {
+	Chip
+}
+
+func (failingChip) RequestLines([]int, []LineConfig) (Lines, error) {
+	return nil, errors.New("device or resource busy")
+}
+
+func TestSetBatch(t *testing.T) {
+	c := setupSim(t)
+
+	if err := SetBatch([]dto.PinMode{output(5, 1), output(6, 0), output(7, 1)}, testSource); err != nil {
+		t.Fatal(err)
+	}
+	for offset, want := range map[int]int{5: 1, 6: 0, 7: 1} {
+		if got := level(c, offset); got != want {
+			t.Errorf("level of pin %d = %d, want %d", offset, got, want)
+		}
+	}
+	stored, err := db.GetPinMap()
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("stored pins = %v, %v, want 3", stored, err)
+	}
+
+	// Reconfiguring a member splits the batch, the others keep their values.
+	if err := SetBool(output(6, 1), testSource); err != nil {
+		t.Fatal(err)
+	}
+	for offset, want := range map[int]int{5: 1, 6: 1, 7: 1} {
+		if got := level(c, offset); got != want {
+			t.Errorf("after split, level of pin %d = %d, want %d", offset, got, want)
+		}
+	}
+
+	for _, pins := range [][]dto.PinMode{
+		{output(5, 1), {Chip: testChip, Pin: 8, Direction: dto.Input}},
+		{output(5, 1), output(5, 0)},
+		{output(5, 1), output(40, 0)},
+	} {
+		if err := SetBatch(pins, testSource); !errors.Is(err, ErrInvalidBatch) {
+			t.Errorf("SetBatch(%+v) = %v, want %v", pins, err, ErrInvalidBatch)
+		}
+	}
+}
+
+func TestSetBatchRollback(t *testing.T) {
+	c := setupSim(t)
+
+	if err := SetBool(output(5, 1), testSource); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel, err := Subscribe(line(17), dto.EventSubscription{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	mu.Lock()
+	chips[testChip] = failingChip{c}
+	mu.Unlock()
+	err = SetBatch([]dto.PinMode{output(5, 0), output(17, 1)}, testSource)
+	mu.Lock()
+	chips[testChip] = c
+	mu.Unlock()
+	if err == nil {
+		t.Fatal("batch succeeded")
+	}
+
+	if got := level(c, 5); got != 1 {
+		t.Errorf("level of pin 5 = %d, want 1", got)
+	}
+	if stored, err := db.GetPin(line(5)); err != nil || stored.Value != 1 {
+		t.Errorf("stored pin 5 = %+v, %v, want value 1", stored, err)
+	}
+	if _, err := db.GetPin(line(17)); !errors.Is(err, db.ErrPinNotFound) {
+		t.Errorf("pin 17 stored after a failed batch: %v", err)
+	}
+
+	// The subscription of the watched pin survives the rollback.
+	if err := SimulateInput(line(17), 1); err != nil {
+		t.Fatal(err)
+	}
+	if ev := receive(t, events); ev.Edge != dto.EdgeRising {
+		t.Errorf("event = %+v, want a rising edge", ev)
+	}
+
+	// Once the batch is held, the subscribers of its pins are closed.
+	if err := SetBatch([]dto.PinMode{output(5, 0), output(17, 1)}, testSource); err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, events)
+}
+
+func TestPulse(t *testing.T) {
+	c := setupSim(t)
+
+	if err := Pulse(line(5), dto.Pulse{Duration: "10ms"}, testSource); err == nil {
+		t.Error("pulsing a pin that is not held succeeded")
+	}
+	if err := SetBool(output(5, 0), testSource); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Pulse(line(5), dto.Pulse{Duration: "20ms", Repeat: 2, Interval: "20ms"}, testSource); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.RLock()
+		_, pulsing := pulses[line(5)]
+		mu.RUnlock()
+		if !pulsing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pulse did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := level(c, 5); got != 0 {
+		t.Errorf("level after the pulse = %d, want 0", got)
+	}
+
+	// A long pulse drives the pin away from rest until it is cancelled.
+	if err := Pulse(line(5), dto.Pulse{Duration: "1m"}, testSource); err != nil {
+		t.Fatal(err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for level(c, 5) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("pin not driven by the pulse")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := CancelPulse(line(5)); err != nil {
+		t.Fatal(err)
+	}
+	if got := level(c, 5); got != 0 {
+		t.Errorf("level after cancelling = %d, want 0", got)
+	}
+	if err := CancelPulse(line(5)); !errors.Is(err, ErrNoPulse) {
+		t.Errorf("CancelPulse = %v, want %v", err, ErrNoPulse)
+	}
+}
+
+func TestInterlock(t *testing.T) {
+	c := setupSim(t)
+
+	if _, err := SetInterlock(dto.Interlock{Name: "motor", Pins: []string{"5", "6"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBool(output(5, 1), testSource); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBool(output(6, 1), testSource); !errors.Is(err, ErrInterlock) {
+		t.Errorf("SetBool = %v, want %v", err, ErrInterlock)
+	}
+	if got := level(c, 6); got != 0 {
+		t.Errorf("level of rejected pin 6 = %d, want 0", got)
+	}
+	if err := SetBool(output(6, 0), testSource); err != nil {
+		t.Errorf("setting pin 6 inactive: %v", err)
+	}
+	if err := Pulse(line(6), dto.Pulse{Duration: "10ms"}, testSource); !errors.Is(err, ErrInterlock) {
+		t.Errorf("Pulse = %v, want %v", err, ErrInterlock)
+	}
+	if err := SetBatch([]dto.PinMode{output(5, 1), output(6, 1)}, testSource); !errors.Is(err, ErrInterlock) {
+		t.Errorf("SetBatch = %v, want %v", err, ErrInterlock)
+	}
+	// Switching over in a single batch is allowed.
+	if err := SetBatch([]dto.PinMode{output(5, 0), output(6, 1)}, testSource); err != nil {
+		t.Errorf("switching over: %v", err)
+	}
+
+	// With a dead time the other pin must stay inactive for a while first.
+	if _, err := SetInterlock(dto.Interlock{Name: "motor", Pins: []string{"5", "6"}, DeadTime: "50ms"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBool(output(6, 0), testSource); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBool(output(5, 1), testSource); !errors.Is(err, ErrInterlock) {
+		t.Errorf("SetBool within the dead time = %v, want %v", err, ErrInterlock)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := SetBool(output(5, 1), testSource); err != nil {
+		t.Errorf("SetBool after the dead time: %v", err)
+	}
+
+	if _, err := SetInterlock(dto.Interlock{Name: "pump", Pins: []string{"5", "7"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBool(output(7, 1), testSource); !errors.Is(err, ErrInterlock) {
+		t.Errorf("SetBool with a second interlock = %v, want %v", err, ErrInterlock)
+	}
+}
+
+func TestRestorePins(t *testing.T) {
+	c := setupSim(t)
+
+	last := output(5, 1)
+	last.Restore = dto.RestoreLast
+	safe := output(6, 1)
+	safe.Restore, safe.SafeValue = dto.RestoreSafe, 0
+	none := output(7, 1)
+	none.Restore = dto.RestoreNone
+	input := dto.PinMode{Chip: testChip, Pin: 8, Direction: dto.Input, Bias: dto.PullUp}
+	first, second := output(10, 1), output(11, 1)
+	if err := db.SetPins([]dto.PinMode{last, safe, none, input, first, second}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetInterlock(dto.Interlock{Name: "motor", Pins: []string{"10", "11"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	report := restorePins()
+	wantRestored := []string{"gpiochip0:10", "gpiochip0:5", "gpiochip0:6", "gpiochip0:8"}
+	if len(report.Restored) != len(wantRestored) {
+		t.Fatalf("restored = %v, want %v", report.Restored, wantRestored)
+	}
+	for i, key := range wantRestored {
+		if report.Restored[i] != key {
+			t.Errorf("restored = %v, want %v", report.Restored, wantRestored)
+			break
+		}
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != "gpiochip0:7" {
+		t.Errorf("skipped = %v, want [gpiochip0:7]", report.Skipped)
+	}
+	if _, failed := report.Failed["gpiochip0:11"]; !failed || len(report.Failed) != 1 {
+		t.Errorf("failed = %v, want gpiochip0:11 rejected by the interlock", report.Failed)
+	}
+	if got := GetRestoreReport(); len(got.Restored) != len(wantRestored) {
+		t.Errorf("GetRestoreReport = %+v", got)
+	}
+
+	for offset, want := range map[int]int{5: 1, 6: 0, 10: 1} {
+		if got := level(c, offset); got != want {
+			t.Errorf("level of pin %d = %d, want %d", offset, got, want)
+		}
+	}
+	if held(7) || held(11) {
+		t.Error("skipped or failed pins are held")
+	}
+	if state, err := GetPin(line(8)); err != nil || state.Value == nil || *state.Value != 1 {
+		t.Errorf("pulled up input 8 = %+v, %v, want value 1", state, err)
+	}
+}
@@ -0,0 +1,171 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// eventBufferSize is the number of events buffered per subscriber before events are dropped.
+const eventBufferSize = 64
+
+// Event represents an edge detected on an input pin.
+type Event struct {
+	Chip            string        `json:"chip"`
+	Pin             int           `json:"pin"`
+	Edge            string        `json:"edge"`  // rising or falling
+	Value           int           `json:"value"` // active state after the edge
+	Time            time.Time     `json:"time"`
+	KernelTimestamp time.Duration `json:"kernel_timestamp"`
+	Seqno           uint32        `json:"seqno"`
+}
+
+type subscriber struct {
+	ch   chan Event
+	edge string
+}
+
+// watch is a line requested with edge detection, shared by all of its subscribers.
+type watch struct {
+	line     dto.Line
+	debounce time.Duration
+	prev     *dto.PinMode // configuration held before the watch, restored once it ends
+
+	evMu sync.RWMutex // guards subs, never held while the line is closed
+	subs map[*subscriber]struct{}
+}
+
+var watches = make(map[dto.Line]*watch)
+
+// handle fans an edge event out to every subscriber interested in it.
+func (w *watch) handle(le gpiocdev.LineEvent) {
+	ev := Event{
+		Chip:            w.line.Chip,
+		Pin:             w.line.Offset,
+		Time:            time.Now(),
+		KernelTimestamp: le.Timestamp,
+		Seqno:           le.LineSeqno,
+	}
+	if le.Type == gpiocdev.LineEventRisingEdge {
+		ev.Edge, ev.Value = dto.EdgeRising, 1
+	} else {
+		ev.Edge, ev.Value = dto.EdgeFalling, 0
+	}
+
+	w.evMu.RLock()
+	defer w.evMu.RUnlock()
+	for s := range w.subs {
+		if s.edge != dto.EdgeBoth && s.edge != ev.Edge {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			log.Printf("GPIO: dropping event on pin %s, subscriber is too slow", w.line)
+		}
+	}
+}
+
+func (w *watch) add(s *subscriber) {
+	w.evMu.Lock()
+	defer w.evMu.Unlock()
+	w.subs[s] = struct{}{}
+}
+
+// remove detaches s and reports how many subscribers are left.
+func (w *watch) remove(s *subscriber) int {
+	w.evMu.Lock()
+	defer w.evMu.Unlock()
+	if _, ok := w.subs[s]; ok {
+		delete(w.subs, s)
+		close(s.ch)
+	}
+	return len(w.subs)
+}
+
+func (w *watch) closeSubscribers() {
+	w.evMu.Lock()
+	defer w.evMu.Unlock()
+	for s := range w.subs {
+		delete(w.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscribe starts streaming the edge events of an input pin.
+// Subscribers of the same pin share a single line request, which is released
+// when the last one unsubscribes. The returned channel is closed when the
+// subscription ends, including when the pin is reconfigured.
+func Subscribe(line dto.Line, sub dto.EventSubscription) (<-chan Event, func(), error) {
+	if !CheckChip() {
+		return nil, nil, errors.New("GPIO chip not initialized")
+	}
+	debounce, err := sub.DebouncePeriod()
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &subscriber{ch: make(chan Event, eventBufferSize), edge: sub.Edge}
+	if s.edge == "" {
+		s.edge = dto.EdgeBoth
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	w, ok := watches[line]
+	if ok {
+		if w.debounce != debounce {
+			return nil, nil, fmt.Errorf("GPIO: pin %s is already watched with debounce %s", line, w.debounce)
+		}
+	} else {
+		w, err = startWatch(line, debounce)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	w.add(s)
+
+	return s.ch, func() { unsubscribe(w, s) }, nil
+}
+
+// startWatch requests pin as an input with edge detection.
+// The caller must hold mu.
+func startWatch(line dto.Line, debounce time.Duration) (*watch, error) {
+	mode := dto.PinMode{Chip: line.Chip, Pin: line.Offset, Direction: dto.Input}
+	w := &watch{line: line, debounce: debounce, subs: make(map[*subscriber]struct{})}
+
+	if prev, held := modes[line]; held {
+		if prev.Direction != dto.Input {
+			return nil, fmt.Errorf("GPIO: pin %s is configured as %s", line, prev.Direction)
+		}
+		w.prev = &prev
+		mode = prev
+	}
+
+	if _, err := requestLine(mode, false, &LineWatch{Debounce: debounce, Handler: w.handle}); err != nil {
+		return nil, err
+	}
+	watches[line] = w
+	return w, nil
+}
+
+func unsubscribe(w *watch, s *subscriber) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if w.remove(s) > 0 || watches[w.line] != w {
+		return
+	}
+
+	releaseLine(w.line)
+	if w.prev != nil {
+		if _, err := requestLine(*w.prev, false, nil); err != nil {
+			log.Println(err)
+		}
+	}
+}
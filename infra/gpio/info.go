@@ -6,8 +6,13 @@ import (
 	"log"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/warthog618/go-gpiocdev"
 )
 
 // GPIOInfo represents the information of a GPIO chip
@@ -21,14 +26,26 @@ type GPIOInfo struct {
 type LineInfo struct {
 	Number    int      `json:"number"`
 	Name      string   `json:"name"`
-	Function  string   `json:"function"`
+	Function  string   `json:"function"` // consumer, or unused
+	Consumer  string   `json:"consumer"`
 	Used      bool     `json:"used"`
-	Direction string   `json:"direction"`
+	Direction string   `json:"direction"` // input or output
+	ActiveLow bool     `json:"activeLow"`
+	Bias      string   `json:"bias,omitempty"`     // pull-up, pull-down or disabled
+	Drive     string   `json:"drive,omitempty"`    // push-pull, open-drain or open-source
+	Edge      string   `json:"edge,omitempty"`     // rising, falling or both
+	Debounce  string   `json:"debounce,omitempty"` // e.g. 10ms
 	Flags     []string `json:"flags"`
 }
 
-// GetGPIOInfo retrieves information about available GPIOs
+// GetGPIOInfo retrieves information about available GPIOs.
+// The lines are read from the opened chips, the lsgpio and gpioinfo tools are
+// only used when no chip could be opened.
 func GetGPIOInfo() ([]GPIOInfo, error) {
+	if CheckChip() {
+		return chipInfo()
+	}
+
 	if _, err := exec.LookPath("lsgpio"); err == nil {
 
 		output, err := exec.Command("lsgpio").Output()
@@ -41,7 +58,7 @@ func GetGPIOInfo() ([]GPIOInfo, error) {
 	if _, err := exec.LookPath("gpioinfo"); err == nil {
 		output, err := exec.Command("gpioinfo").Output()
 		if err != nil {
-			return nil, fmt.Errorf("error executing lsgpio: %v", err)
+			return nil, fmt.Errorf("error executing gpioinfo: %v", err)
 		}
 		return parseGPIOInfo(string(output))
 
@@ -50,10 +67,107 @@ func GetGPIOInfo() ([]GPIOInfo, error) {
 	return nil, errors.New("lsgpio and gpioinfo command not found")
 }
 
-// parseGPIOInfo processes the output of the gpioinfo command
+// chipInfo reads the information of every line of the opened chips.
+func chipInfo() ([]GPIOInfo, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(chips))
+	for name := range chips {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gpioInfo := make([]GPIOInfo, 0, len(names))
+	for _, name := range names {
+		c := chips[name]
+		chip := GPIOInfo{
+			DeviceName: c.Name(),
+			Name:       c.Label(),
+			Lines:      make([]LineInfo, 0, c.Lines()),
+		}
+		for offset := 0; offset < c.Lines(); offset++ {
+			info, err := c.LineInfo(offset)
+			if err != nil {
+				return nil, fmt.Errorf("error reading line info for line %s:%d: %w", name, offset, err)
+			}
+			chip.Lines = append(chip.Lines, newLineInfo(info))
+		}
+		gpioInfo = append(gpioInfo, chip)
+	}
+	return gpioInfo, nil
+}
+
+// newLineInfo converts the line info reported by the kernel.
+func newLineInfo(info gpiocdev.LineInfo) LineInfo {
+	cfg := info.Config
+	line := LineInfo{
+		Number:    info.Offset,
+		Name:      info.Name,
+		Function:  "unused",
+		Consumer:  info.Consumer,
+		Used:      info.Used,
+		Direction: "input",
+		ActiveLow: cfg.ActiveLow,
+		Flags:     []string{},
+	}
+	if info.Consumer != "" {
+		line.Function = info.Consumer
+	}
+	if info.Used {
+		line.Flags = append(line.Flags, "used")
+	}
+	if cfg.ActiveLow {
+		line.Flags = append(line.Flags, "active-low")
+	}
+
+	if cfg.Direction == gpiocdev.LineDirectionOutput {
+		line.Direction = "output"
+		switch cfg.Drive {
+		case gpiocdev.LineDrivePushPull:
+			line.Drive = dto.PushPull
+		case gpiocdev.LineDriveOpenDrain:
+			line.Drive = dto.OpenDrain
+		case gpiocdev.LineDriveOpenSource:
+			line.Drive = dto.OpenSource
+		}
+	}
+
+	switch cfg.Bias {
+	case gpiocdev.LineBiasPullUp:
+		line.Bias = dto.PullUp
+	case gpiocdev.LineBiasPullDown:
+		line.Bias = dto.PullDown
+	case gpiocdev.LineBiasDisabled:
+		line.Bias = dto.BiasDisabled
+	}
+
+	switch cfg.EdgeDetection {
+	case gpiocdev.LineEdgeRising:
+		line.Edge = dto.EdgeRising
+	case gpiocdev.LineEdgeFalling:
+		line.Edge = dto.EdgeFalling
+	case gpiocdev.LineEdgeBoth:
+		line.Edge = dto.EdgeBoth
+	}
+
+	if cfg.Debounced {
+		line.Debounce = cfg.DebouncePeriod.String()
+	}
+	for _, flag := range []string{line.Bias, line.Drive, line.Edge} {
+		if flag != "" {
+			line.Flags = append(line.Flags, flag)
+		}
+	}
+	return line
+}
+
+// parseGPIOInfo processes the output of the gpioinfo command, in the libgpiod v1
+// and v2 formats
 func parseGPIOInfo(output string) ([]GPIOInfo, error) {
 	chipRegex := regexp.MustCompile(`(gpiochip\d+) - (\d+) lines:`)
-	lineRegex := regexp.MustCompile(`\s*line\s*(\d+):\s*["']?(\w+)["']?\s*(\S+)\s*(input|output)\s*(active-high|active-low)\s*(?:\s*\[([\w\s]+)\])?`)
+	lineRegex := regexp.MustCompile(`\s*line\s*(\d+):\s*["']?(\w+)["']?\s+(\S+)\s+(input|output)\s+(active-high|active-low)\s*(?:\s*\[([\w\s-]+)\])?`)
+	lineV2Regex := regexp.MustCompile(`^\s*line\s+(\d+):\s+(?:"([^"]*)"|unnamed)\s+(input|output)(.*)$`)
 
 	var gpioInfo []GPIOInfo
 	var currentChip *GPIOInfo
@@ -77,10 +191,28 @@ func parseGPIOInfo(output string) ([]GPIOInfo, error) {
 				Direction: lineMatch[4],
 			}
 
+			if lineInfo.Name == "unnamed" {
+				lineInfo.Name = ""
+			}
 			lineInfo.Used = strings.Contains(lineMatch[6], "used")
+			if lineInfo.Function != "unused" {
+				lineInfo.Consumer = lineInfo.Function
+			}
+			lineInfo.ActiveLow = lineMatch[5] == "active-low"
 
 			lineInfo.Flags = []string{lineMatch[5]}
+			for _, flag := range strings.Fields(lineMatch[6]) {
+				if flag != "used" {
+					lineInfo.Flags = append(lineInfo.Flags, flag)
+				}
+			}
+			applyFlags(&lineInfo)
 
+			if currentChip != nil {
+				currentChip.Lines = append(currentChip.Lines, lineInfo)
+			}
+		} else if lineMatch := lineV2Regex.FindStringSubmatch(line); lineMatch != nil {
+			lineInfo := parseGPIOInfoV2Line(lineMatch)
 			if currentChip != nil {
 				currentChip.Lines = append(currentChip.Lines, lineInfo)
 			}
@@ -94,10 +226,70 @@ func parseGPIOInfo(output string) ([]GPIOInfo, error) {
 	return gpioInfo, nil
 }
 
-// parseGPIOOutput processes the output of the lsgpio command
+// parseGPIOInfoV2Line converts a line matched in the libgpiod v2 gpioinfo
+// output, where the attributes follow the direction, e.g.
+//
+//	line  17:	"GPIO17"	output active-low consumer="raspController"
+func parseGPIOInfoV2Line(match []string) LineInfo {
+	lineInfo := LineInfo{
+		Number:    parseInt(match[1]),
+		Name:      match[2],
+		Function:  "unused",
+		Direction: match[3],
+		Flags:     []string{},
+	}
+
+	attrRegex := regexp.MustCompile(`([\w-]+)=("[^"]*"|\S+)|(\S+)`)
+	for _, attr := range attrRegex.FindAllStringSubmatch(match[4], -1) {
+		key, value := attr[1], parseCleanString(attr[2])
+		switch key {
+		case "consumer":
+			lineInfo.Consumer = value
+			lineInfo.Function = value
+			lineInfo.Used = true
+		case "bias", "drive", "edges":
+			lineInfo.Flags = append(lineInfo.Flags, value)
+		case "debounce-period":
+			lineInfo.Debounce = value
+		case "":
+			if attr[3] == "used" {
+				lineInfo.Used = true
+				continue
+			}
+			lineInfo.Flags = append(lineInfo.Flags, attr[3])
+		}
+	}
+	applyFlags(&lineInfo)
+	return lineInfo
+}
+
+// applyFlags fills the line attributes named in its flags.
+func applyFlags(lineInfo *LineInfo) {
+	for _, flag := range lineInfo.Flags {
+		switch flag {
+		case "active-low":
+			lineInfo.ActiveLow = true
+		case dto.PullUp, dto.PullDown:
+			lineInfo.Bias = flag
+		case "bias-disabled", dto.BiasDisabled:
+			lineInfo.Bias = dto.BiasDisabled
+		case dto.PushPull, dto.OpenDrain, dto.OpenSource:
+			lineInfo.Drive = flag
+		case dto.EdgeRising, dto.EdgeFalling, dto.EdgeBoth:
+			lineInfo.Edge = flag
+		}
+	}
+}
+
+// parseLsGPIO processes the output of the lsgpio command, e.g.
+//
+//	GPIO chip: gpiochip0, "pinctrl-bcm2711", 58 GPIO lines
+//		line 17: "GPIO17" "raspController" [used, input, pull-up, edge-rising, debounce_period=10000usec]
+//
+// Lines that cannot be parsed are skipped.
 func parseLsGPIO(output string) ([]GPIOInfo, error) {
 	chipRegex := regexp.MustCompile(`GPIO chip: (\S+), "(.+)", (\d+) GPIO lines`)
-	lineRegex := regexp.MustCompile(`\s*line\s*(\d+):\s*"(.+)"\s*(used|unused)\s*\[([^\[\]]*)\]\s*`)
+	lineRegex := regexp.MustCompile(`^\s*line\s+(\d+):\s+(?:"([^"]*)"|unnamed)\s+(?:"([^"]*)"|unused)(?:\s+\[([^\]]*)\])?\s*$`)
 
 	var gpioInfo []GPIOInfo
 	var currentChip *GPIOInfo
@@ -112,39 +304,8 @@ func parseLsGPIO(output string) ([]GPIOInfo, error) {
 				DeviceName: chipMatch[1],
 				Name:       chipMatch[2],
 			}
-		} else if lineMatch := lineRegex.FindStringSubmatch(line); lineMatch != nil {
-			lineInfo := LineInfo{
-				Number:   parseInt(lineMatch[1]),
-				Name:     lineMatch[2],
-				Function: lineMatch[3],
-				Used:     strings.Contains(lineMatch[4], "used"),
-			}
-
-			if strings.Contains(lineMatch[4], "output") {
-				lineInfo.Direction = "output"
-			} else {
-				lineInfo.Direction = "input"
-			}
-
-			lineInfo.Flags = strings.Split(lineMatch[4], ", ")[1:]
-			if currentChip != nil {
-				currentChip.Lines = append(currentChip.Lines, lineInfo)
-			}
-		} else {
-			// Handle non-matching lines (potentially gpiochip0)
-			if currentChip != nil {
-				// Create a simple LineInfo assuming it's part of the current chip
-				lineInfo := LineInfo{
-					Function:  "unused",
-					Used:      false,
-					Direction: "unknown",
-					Flags:     []string{},
-				}
-
-				// You could attempt to extract at least the number and name here if possible
-
-				currentChip.Lines = append(currentChip.Lines, lineInfo)
-			}
+		} else if lineMatch := lineRegex.FindStringSubmatch(line); lineMatch != nil && currentChip != nil {
+			currentChip.Lines = append(currentChip.Lines, parseLsGPIOLine(lineMatch))
 		}
 	}
 
@@ -155,6 +316,55 @@ func parseLsGPIO(output string) ([]GPIOInfo, error) {
 	return gpioInfo, nil
 }
 
+// parseLsGPIOLine converts a line matched in the lsgpio output, whose
+// attributes are a comma separated list of flags and debounce_period.
+func parseLsGPIOLine(match []string) LineInfo {
+	lineInfo := LineInfo{
+		Number:    parseInt(match[1]),
+		Name:      match[2],
+		Function:  "unused",
+		Consumer:  match[3],
+		Direction: "input",
+		Flags:     []string{},
+	}
+	if lineInfo.Consumer != "" {
+		lineInfo.Function = lineInfo.Consumer
+	}
+
+	var rising, falling bool
+	for _, attr := range strings.Split(match[4], ",") {
+		attr = strings.TrimSpace(attr)
+		switch {
+		case attr == "":
+		case attr == "used":
+			lineInfo.Used = true
+		case attr == "input", attr == "output":
+			lineInfo.Direction = attr
+		case attr == "edge-rising":
+			rising = true
+		case attr == "edge-falling":
+			falling = true
+		case strings.HasPrefix(attr, "debounce_period="):
+			usec, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(attr, "debounce_period="), "usec"))
+			if err == nil && usec > 0 {
+				lineInfo.Debounce = (time.Duration(usec) * time.Microsecond).String()
+			}
+		default:
+			lineInfo.Flags = append(lineInfo.Flags, attr)
+		}
+	}
+	switch {
+	case rising && falling:
+		lineInfo.Flags = append(lineInfo.Flags, dto.EdgeBoth)
+	case rising:
+		lineInfo.Flags = append(lineInfo.Flags, dto.EdgeRising)
+	case falling:
+		lineInfo.Flags = append(lineInfo.Flags, dto.EdgeFalling)
+	}
+	applyFlags(&lineInfo)
+	return lineInfo
+}
+
 // parseInt is a helper to parse strings as integers safely
 func parseInt(s string) int {
 	num, _ := strconv.Atoi(s)
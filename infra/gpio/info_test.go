@@ -0,0 +1,153 @@
+package gpio
+
+import "testing"
+
+// Outputs captured on a Raspberry Pi 4, trimmed to a few lines.
+const (
+	gpioinfoV1Output = `gpiochip0 - 58 lines:
+	line   0:     "ID_SDA"       unused   input  active-high
+	line   4:      "GPIO4" "raspController" output active-low [used open-drain]
+	line  17:     "GPIO17" "raspController" input active-high [used pull-up]
+	line  18:     "GPIO18"       unused   input  active-high [bias-disabled]
+gpiochip1 - 8 lines:
+	line   0:      unnamed       unused   input  active-high
+	line   2:      unnamed "led1" output active-high [used]
+`
+
+	gpioinfoV2Output = `gpiochip0 - 58 lines:
+	line   0:	"ID_SDA"        	input
+	line   4:	"GPIO4"         	output active-low drive=open-drain consumer="raspController"
+	line  17:	"GPIO17"        	input bias=pull-up edges=both debounce-period=10ms consumer="raspController"
+	line  18:	"GPIO18"        	input bias=disabled
+gpiochip1 - 8 lines:
+	line   0:	unnamed         	input
+	line   2:	unnamed         	output consumer="led1"
+`
+
+	lsgpioOutput = `GPIO chip: gpiochip0, "pinctrl-bcm2711", 58 GPIO lines
+	line  0: "ID_SDA" unused [input]
+	line  4: "GPIO4" "raspController" [used, output, active-low, open-drain]
+	line 17: "GPIO17" "raspController" [used, input, pull-up, edge-rising, edge-falling, debounce_period=10000usec]
+	line 18: "GPIO18" unused [input, bias-disabled]
+	some line lsgpio does not print
+GPIO chip: gpiochip1, "raspberrypi-exp-gpio", 8 GPIO lines
+	line  0: unnamed unused [input]
+	line  2: unnamed "led1" [used, output]
+`
+)
+
+type wantLine struct {
+	Number    int
+	Name      string
+	Consumer  string
+	Used      bool
+	Direction string
+	ActiveLow bool
+	Bias      string
+	Drive     string
+	Edge      string
+	Debounce  string
+}
+
+func TestParseGPIOInfo(t *testing.T) {
+	v1 := map[string][]wantLine{
+		"gpiochip0": {
+			{Number: 0, Name: "ID_SDA", Direction: "input"},
+			{Number: 4, Name: "GPIO4", Consumer: "raspController", Used: true, Direction: "output", ActiveLow: true, Drive: "open-drain"},
+			{Number: 17, Name: "GPIO17", Consumer: "raspController", Used: true, Direction: "input", Bias: "pull-up"},
+			{Number: 18, Name: "GPIO18", Direction: "input", Bias: "disabled"},
+		},
+		"gpiochip1": {
+			{Number: 0, Direction: "input"},
+			{Number: 2, Consumer: "led1", Used: true, Direction: "output"},
+		},
+	}
+	// libgpiod v2 reports the edge detection and the debounce period, but
+	// only the consumer tells a used line.
+	v2 := map[string][]wantLine{
+		"gpiochip0": {
+			{Number: 0, Name: "ID_SDA", Direction: "input"},
+			{Number: 4, Name: "GPIO4", Consumer: "raspController", Used: true, Direction: "output", ActiveLow: true, Drive: "open-drain"},
+			{Number: 17, Name: "GPIO17", Consumer: "raspController", Used: true, Direction: "input", Bias: "pull-up", Edge: "both", Debounce: "10ms"},
+			{Number: 18, Name: "GPIO18", Direction: "input", Bias: "disabled"},
+		},
+		"gpiochip1": v1["gpiochip1"],
+	}
+
+	for _, tt := range []struct {
+		name   string
+		output string
+		want   map[string][]wantLine
+	}{
+		{"v1", gpioinfoV1Output, v1},
+		{"v2", gpioinfoV2Output, v2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseGPIOInfo(tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkInfo(t, info, []string{"gpiochip0", "gpiochip1"}, tt.want)
+		})
+	}
+}
+
+func TestParseLsGPIO(t *testing.T) {
+	info, err := parseLsGPIO(lsgpioOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkInfo(t, info, []string{"pinctrl-bcm2711", "raspberrypi-exp-gpio"}, map[string][]wantLine{
+		"gpiochip0": {
+			{Number: 0, Name: "ID_SDA", Direction: "input"},
+			{Number: 4, Name: "GPIO4", Consumer: "raspController", Used: true, Direction: "output", ActiveLow: true, Drive: "open-drain"},
+			{Number: 17, Name: "GPIO17", Consumer: "raspController", Used: true, Direction: "input", Bias: "pull-up", Edge: "both", Debounce: "10ms"},
+			{Number: 18, Name: "GPIO18", Direction: "input", Bias: "disabled"},
+		},
+		"gpiochip1": {
+			{Number: 0, Direction: "input"},
+			{Number: 2, Consumer: "led1", Used: true, Direction: "output"},
+		},
+	})
+}
+
+func checkInfo(t *testing.T, info []GPIOInfo, names []string, want map[string][]wantLine) {
+	t.Helper()
+	if len(info) != len(names) {
+		t.Fatalf("got %d chips, want %d: %+v", len(info), len(names), info)
+	}
+	for i, chip := range info {
+		if chip.Name != names[i] {
+			t.Errorf("chip %d name = %q, want %q", i, chip.Name, names[i])
+		}
+		lines := want[chip.DeviceName]
+		if len(chip.Lines) != len(lines) {
+			t.Errorf("%s: got %d lines, want %d: %+v", chip.DeviceName, len(chip.Lines), len(lines), chip.Lines)
+			continue
+		}
+		for j, line := range chip.Lines {
+			got := wantLine{
+				Number:    line.Number,
+				Name:      line.Name,
+				Consumer:  line.Consumer,
+				Used:      line.Used,
+				Direction: line.Direction,
+				ActiveLow: line.ActiveLow,
+				Bias:      line.Bias,
+				Drive:     line.Drive,
+				Edge:      line.Edge,
+				Debounce:  line.Debounce,
+			}
+			if got != lines[j] {
+				t.Errorf("%s line %d = %+v, want %+v", chip.DeviceName, line.Number, got, lines[j])
+			}
+			wantFunction := "unused"
+			if lines[j].Consumer != "" {
+				wantFunction = lines[j].Consumer
+			}
+			if line.Function != wantFunction {
+				t.Errorf("%s line %d function = %q, want %q", chip.DeviceName, line.Number, line.Function, wantFunction)
+			}
+		}
+	}
+}
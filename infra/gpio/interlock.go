@@ -0,0 +1,198 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+var (
+	// ErrInterlock is returned when a change would violate an interlock.
+	ErrInterlock = errors.New("interlock violated")
+	// ErrInvalidInterlock is returned when the pins of an interlock cannot be grouped.
+	ErrInvalidInterlock = errors.New("invalid interlock")
+)
+
+var (
+	interlocks   = make(map[string]dto.Interlock)
+	lastInactive = make(map[dto.Line]time.Time) // when a line last went from active to inactive
+)
+
+// loadInterlocks reads the interlocks stored in the database.
+func loadInterlocks() {
+	stored, err := db.GetInterlocks()
+	if err != nil {
+		log.Println("GPIO: Error reading interlocks:", err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for name, interlock := range stored {
+		interlocks[name] = interlock
+	}
+}
+
+// GetInterlocks returns every interlock, sorted by name.
+func GetInterlocks() []dto.Interlock {
+	mu.RLock()
+	defer mu.RUnlock()
+	return sortedInterlocks()
+}
+
+// SetInterlock resolves the pins of interlock and stores it, replacing the
+// interlock of the same name. It is refused if the pins currently violate it.
+func SetInterlock(interlock dto.Interlock) (dto.Interlock, error) {
+	interlock.Lines = make([]dto.Line, 0, len(interlock.Pins))
+	seen := make(map[dto.Line]bool, len(interlock.Pins))
+	for _, ref := range interlock.Pins {
+		line, err := ResolvePin(ref)
+		if err != nil {
+			return dto.Interlock{}, err
+		}
+		if seen[line] {
+			return dto.Interlock{}, fmt.Errorf("%w: pin %s is repeated", ErrInvalidInterlock, line)
+		}
+		seen[line] = true
+		interlock.Lines = append(interlock.Lines, line)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var active []dto.Line
+	for _, line := range interlock.Lines {
+		if lineActive(line) {
+			active = append(active, line)
+		}
+	}
+	if len(active) > 1 {
+		return dto.Interlock{}, fmt.Errorf("%w: %s allows one active pin at a time, but %s and %s are active",
+			ErrInterlock, interlock.Name, active[0], active[1])
+	}
+
+	if err := db.SetInterlock(interlock); err != nil {
+		return dto.Interlock{}, fmt.Errorf("GPIO: Error storing interlock: %w", err)
+	}
+	interlocks[interlock.Name] = interlock
+	log.Printf("GPIO: Interlock %s set on %d pins", interlock.Name, len(interlock.Lines))
+	return interlock, nil
+}
+
+// DeleteInterlock removes an interlock by name.
+func DeleteInterlock(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := db.DeleteInterlock(name); err != nil {
+		return err
+	}
+	delete(interlocks, name)
+	log.Printf("GPIO: Interlock %s removed", name)
+	return nil
+}
+
+// sortedInterlocks returns the interlocks sorted by name.
+// The caller must hold mu.
+func sortedInterlocks() []dto.Interlock {
+	list := make([]dto.Interlock, 0, len(interlocks))
+	for _, interlock := range interlocks {
+		list = append(list, interlock)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// modeActive reports whether mode drives its line to the active level.
+func modeActive(mode dto.PinMode) bool {
+	switch mode.Direction {
+	case dto.Output:
+		return mode.Value == 1
+	case dto.PWM:
+		return mode.DutyCycle > 0
+	}
+	return false
+}
+
+// lineActive reports whether a held line is active or pulsing.
+// The caller must hold mu.
+func lineActive(line dto.Line) bool {
+	mode, held := modes[line]
+	if !held {
+		return false
+	}
+	_, pulsing := pulses[line]
+	return modeActive(mode) || pulsing
+}
+
+// checkInterlocks returns an ErrInterlock error describing the constraint
+// that applying changes would violate. Otherwise it returns the lines the
+// changes switch from active to inactive, to be passed to markInactive once
+// they are applied.
+// The caller must hold mu.
+func checkInterlocks(changes []dto.PinMode) ([]dto.Line, error) {
+	next := make(map[dto.Line]bool, len(changes))
+	var off []dto.Line
+	for _, change := range changes {
+		next[change.Line()] = modeActive(change)
+		if lineActive(change.Line()) && !modeActive(change) {
+			off = append(off, change.Line())
+		}
+	}
+	if len(interlocks) == 0 {
+		return off, nil
+	}
+
+	now := time.Now()
+	for _, interlock := range sortedInterlocks() {
+		deadTime, _ := interlock.DeadTimePeriod()
+
+		var active []dto.Line
+		for _, line := range interlock.Lines {
+			isActive, changed := next[line]
+			if !changed {
+				isActive = lineActive(line)
+			}
+			if isActive {
+				active = append(active, line)
+			}
+		}
+		if len(active) > 1 {
+			return nil, fmt.Errorf("%w: %s allows one active pin at a time, %s and %s would both be active",
+				ErrInterlock, interlock.Name, active[0], active[1])
+		}
+		if len(active) == 0 || deadTime == 0 || lineActive(active[0]) {
+			continue
+		}
+
+		// active[0] is switched on: every other pin must have been off for the dead time.
+		for _, line := range interlock.Lines {
+			if line == active[0] {
+				continue
+			}
+			since, ok := lastInactive[line]
+			if isActive, changed := next[line]; changed && !isActive && lineActive(line) {
+				since, ok = now, true
+			}
+			if ok && now.Sub(since) < deadTime {
+				return nil, fmt.Errorf("%w: %s requires %s between %s going inactive and %s going active, %s left",
+					ErrInterlock, interlock.Name, deadTime, line, active[0], (deadTime - now.Sub(since)).Round(time.Millisecond))
+			}
+		}
+	}
+	return off, nil
+}
+
+// markInactive records that lines went from active to inactive now.
+// The caller must hold mu.
+func markInactive(lines []dto.Line) {
+	now := time.Now()
+	for _, line := range lines {
+		lastInactive[line] = now
+	}
+}
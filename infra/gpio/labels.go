@@ -0,0 +1,115 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+var (
+	// ErrUnknownPin is returned when a pin reference matches no pin.
+	ErrUnknownPin = errors.New("unknown pin")
+	// ErrAmbiguousPin is returned when a line name is used by more than one line.
+	ErrAmbiguousPin = errors.New("ambiguous pin")
+)
+
+// BoardPin is a pin of the GPIO header together with its label, if any.
+type BoardPin struct {
+	vchiq.HeaderPin
+	Label *dto.PinLabel `json:"label,omitempty"`
+}
+
+// BoardMap is the GPIO header of the detected board.
+type BoardMap struct {
+	Board  string         `json:"board"`
+	Chip   string         `json:"chip"` // chip the header lines belong to
+	Header []BoardPin     `json:"header"`
+	Labels []dto.PinLabel `json:"labels"`
+}
+
+// ResolvePin returns the line referenced by ref, which is one of:
+//   - an offset on the default chip, e.g. 17
+//   - a chip and an offset, e.g. gpiochip1:5 or 1:5
+//   - a pin label
+//   - a kernel line name, e.g. GPIO17, searched on all chips
+func ResolvePin(ref string) (dto.Line, error) {
+	if offset, err := strconv.Atoi(ref); err == nil {
+		if offset < 0 {
+			return dto.Line{}, fmt.Errorf("%w: %s", ErrUnknownPin, ref)
+		}
+		return DefaultLine(offset), nil
+	}
+
+	if chip, offsetRef, ok := strings.Cut(ref, ":"); ok {
+		offset, err := strconv.Atoi(offsetRef)
+		if err != nil || offset < 0 {
+			return dto.Line{}, fmt.Errorf("%w: %s", ErrUnknownPin, ref)
+		}
+		if _, err := strconv.Atoi(chip); err == nil {
+			chip = "gpiochip" + chip
+		}
+		return dto.Line{Chip: chip, Offset: offset}, nil
+	}
+
+	label, err := db.FindLabel(ref)
+	if errors.Is(err, db.ErrLabelNotFound) {
+		return LookupLineName(ref)
+	} else if err != nil {
+		return dto.Line{}, err
+	}
+	return label.Line(), nil
+}
+
+// SetLabel labels a pin.
+func SetLabel(label dto.PinLabel) error {
+	return db.SetLabel(label)
+}
+
+// DeleteLabel removes the label of a pin.
+func DeleteLabel(line dto.Line) error {
+	return db.DeleteLabel(line)
+}
+
+// GetBoardMap returns the header of the detected board with the pin labels.
+// The header lines are taken from the default chip. Boards without a known
+// header only report the labels.
+func GetBoardMap() (BoardMap, error) {
+	labels, err := db.GetLabels()
+	if err != nil {
+		return BoardMap{}, err
+	}
+
+	board := BoardMap{Labels: make([]dto.PinLabel, 0, len(labels))}
+	for _, l := range labels {
+		board.Labels = append(board.Labels, l)
+	}
+	sort.Slice(board.Labels, func(i, j int) bool {
+		return board.Labels[i].Line().String() < board.Labels[j].Line().String()
+	})
+
+	board.Board, err = vchiq.GetDeviceName()
+	if err != nil {
+		return board, nil
+	}
+	header, err := vchiq.GetHeader(board.Board)
+	if err != nil {
+		return board, nil
+	}
+	board.Chip = DefaultLine(0).Chip
+	for _, h := range header {
+		bp := BoardPin{HeaderPin: h}
+		if h.BCM >= 0 {
+			if l, ok := labels[DefaultLine(h.BCM).String()]; ok {
+				bp.Label = &l
+			}
+		}
+		board.Header = append(board.Header, bp)
+	}
+	return board, nil
+}
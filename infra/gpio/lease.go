@@ -0,0 +1,223 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+var (
+	// ErrNoLease is returned when renewing or removing a pin without a watchdog.
+	ErrNoLease = errors.New("no lease on pin")
+	// ErrLeaseTripped is returned when renewing a watchdog that already drove its pin to the safe value.
+	ErrLeaseTripped = errors.New("lease expired, the pin was driven to its safe value")
+)
+
+// LeaseState is the state of the watchdog of a pin.
+type LeaseState struct {
+	Chip      string     `json:"chip"`
+	Pin       int        `json:"pin"`
+	Timeout   string     `json:"timeout"`
+	SafeValue int        `json:"safe_value"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	Tripped   bool       `json:"tripped"`
+	TrippedAt *time.Time `json:"tripped_at,omitempty"`
+}
+
+// lease is the watchdog of an output pin.
+type lease struct {
+	state   LeaseState
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+var leases = make(map[dto.Line]*lease)
+
+// Arm starts, or restarts, the watchdog of an output pin. Unless renewed with
+// Heartbeat within the timeout, the pin is driven to its safe value.
+func Arm(req dto.Lease) (LeaseState, error) {
+	if !CheckChip() {
+		return LeaseState{}, errors.New("GPIO chip not initialized")
+	}
+	timeout, err := req.TimeoutPeriod()
+	if err != nil {
+		return LeaseState{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	line := req.Line()
+	mode, held := modes[line]
+	if !held || (mode.Direction != dto.Output && mode.Direction != dto.PWM) {
+		return LeaseState{}, fmt.Errorf("GPIO: pin %s is not configured as output", line)
+	}
+	if req.SafeValue == nil {
+		req.SafeValue = &mode.SafeValue
+	}
+	if err := db.SetLease(req); err != nil {
+		return LeaseState{}, fmt.Errorf("GPIO: Error storing lease: %w", err)
+	}
+
+	arm(req, timeout)
+	log.Printf("GPIO: Pin %s watchdog armed for %s", line, timeout)
+	return leases[line].state, nil
+}
+
+// arm (re)starts the watchdog described by req.
+// The caller must hold mu.
+func arm(req dto.Lease, timeout time.Duration) {
+	line := req.Line()
+	if l, ok := leases[line]; ok {
+		l.timer.Stop()
+	}
+
+	l := &lease{
+		state: LeaseState{
+			Chip:      line.Chip,
+			Pin:       line.Offset,
+			Timeout:   timeout.String(),
+			SafeValue: *req.SafeValue,
+			ExpiresAt: time.Now().Add(timeout),
+		},
+		timeout: timeout,
+	}
+	l.timer = time.AfterFunc(timeout, func() { expire(line, l) })
+	leases[line] = l
+}
+
+// Heartbeat renews the watchdog of a pin for another timeout.
+func Heartbeat(line dto.Line) (LeaseState, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	l, ok := leases[line]
+	if !ok {
+		return LeaseState{}, ErrNoLease
+	}
+	if l.state.Tripped {
+		return l.state, ErrLeaseTripped
+	}
+	l.state.ExpiresAt = time.Now().Add(l.timeout)
+	l.timer.Reset(l.timeout)
+	return l.state, nil
+}
+
+// Disarm removes the watchdog of a pin, leaving the pin as it is.
+func Disarm(line dto.Line) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	l, ok := leases[line]
+	if !ok {
+		return ErrNoLease
+	}
+	l.timer.Stop()
+	delete(leases, line)
+	if err := db.DeleteLease(line); err != nil {
+		return fmt.Errorf("GPIO: Error removing lease: %w", err)
+	}
+	log.Printf("GPIO: Pin %s watchdog disarmed", line)
+	return nil
+}
+
+// GetLeases returns the watchdogs of every pin, sorted by line.
+func GetLeases() []LeaseState {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]LeaseState, 0, len(leases))
+	for _, l := range leases {
+		list = append(list, l.state)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Chip != list[j].Chip {
+			return list[i].Chip < list[j].Chip
+		}
+		return list[i].Pin < list[j].Pin
+	})
+	return list
+}
+
+// leaseOf returns the watchdog state of line.
+// The caller must hold mu.
+func leaseOf(line dto.Line) *LeaseState {
+	if l, ok := leases[line]; ok {
+		state := l.state
+		return &state
+	}
+	return nil
+}
+
+// expire drives the pin of an expired watchdog to its safe value. A tripped
+// watchdog stays reported until it is armed again or disarmed.
+func expire(line dto.Line, l *lease) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// The timer may have fired while a heartbeat was waiting for mu.
+	if leases[line] != l || l.state.Tripped || time.Now().Before(l.state.ExpiresAt) {
+		return
+	}
+
+	now := time.Now()
+	l.state.Tripped = true
+	l.state.TrippedAt = &now
+	if err := db.DeleteLease(line); err != nil {
+		log.Printf("GPIO: Error removing lease of pin %s: %v", line, err)
+	}
+
+	mode, held := modes[line]
+	if !held || (mode.Direction != dto.Output && mode.Direction != dto.PWM) {
+		log.Printf("GPIO: Pin %s watchdog expired, pin is no longer an output and was left untouched", line)
+		return
+	}
+
+	old := mode
+	mode.Direction = dto.Output
+	mode.Value = l.state.SafeValue
+	off, err := checkInterlocks([]dto.PinMode{mode})
+	if err != nil {
+		log.Printf("GPIO: Pin %s watchdog expired, not driven to %d: %v", line, mode.Value, err)
+		return
+	}
+	if err := applyMode(mode); err != nil {
+		log.Printf("GPIO: Pin %s watchdog expired, error driving it to %d: %v", line, mode.Value, err)
+		return
+	}
+	markInactive(off)
+	audit(dto.ChangeWatchdog, &old, mode, dto.Source{Type: dto.SourceWatchdog}, "no heartbeat for "+l.timeout.String())
+	if err := db.SetPin(mode); err != nil {
+		log.Printf("GPIO: Error setting pin value in database: %v", err)
+	}
+	log.Printf("GPIO: Pin %s watchdog expired after %s without heartbeat, driven to safe value %d",
+		line, l.timeout, mode.Value)
+}
+
+// restoreLeases arms the watchdogs stored in the database again, with a full
+// timeout, so that a pin restored on startup is not left unattended.
+func restoreLeases() {
+	stored, err := db.GetLeases()
+	if err != nil {
+		log.Println("GPIO: Error reading leases:", err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, req := range stored {
+		timeout, err := req.TimeoutPeriod()
+		if err != nil || req.SafeValue == nil {
+			continue
+		}
+		arm(req, timeout)
+	}
+	if len(stored) > 0 {
+		log.Printf("GPIO: %d watchdogs armed again", len(stored))
+	}
+}
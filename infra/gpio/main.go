@@ -10,74 +10,157 @@ import (
 	"github.com/gabrielmoura/raspController/configs"
 	"github.com/gabrielmoura/raspController/infra/db"
 	"github.com/gabrielmoura/raspController/internal/dto"
-	"github.com/warthog618/go-gpiocdev"
 )
 
 var (
-	Chip  *gpiocdev.Chip
-	lines = make(map[int]*gpiocdev.Line)
-	mu    sync.RWMutex // RWMutex allows multiple concurrent readings
-	once  sync.Once    // Ensures that initialization only occurs once
+	chips       = make(map[string]Chip) // keyed by chip name, e.g. gpiochip0
+	defaultChip string                  // chip of the lines addressed by offset only
+	lines       = make(map[dto.Line]lineHandle)
+	modes       = make(map[dto.Line]dto.PinMode) // configuration of the lines currently held
+	lineNames   = make(map[string][]dto.Line)    // kernel line names, e.g. GPIO17, to their lines
+	mu          sync.RWMutex                     // RWMutex allows multiple concurrent readings
+	once        sync.Once                        // Ensures that initialization only occurs once
 )
 
-func initializeChip(ctx context.Context) error {
+// lineHandle is a held line, requested on its own or as a member of a batch.
+type lineHandle interface {
+	Value() (int, error)
+	SetValue(value int) error
+}
+
+// initializeChips opens every GPIO chip of the configured driver.
+func initializeChips(ctx context.Context) error {
 	mu.Lock()
 	defer mu.Unlock()
 
-	c, err := gpiocdev.NewChip("gpiochip0", gpiocdev.WithConsumer(configs.Conf.AppName))
+	driver, err := newDriver(configs.Conf)
 	if err != nil {
-		return fmt.Errorf("Error opening GPIO chip: %w", err)
+		return err
 	}
 
-	Chip = c
+	defaultChip = configs.Conf.GPIOChip
+	if sim, ok := driver.(*gpioSimDriver); ok {
+		// The kernel numbers the simulated chip after the existing ones.
+		defaultChip = sim.chip
+	}
+	for _, name := range driver.Chips() {
+		c, err := driver.OpenChip(name, configs.Conf.AppName)
+		if err != nil {
+			log.Printf("Error opening GPIO chip %s: %v", name, err)
+			continue
+		}
+		chips[name] = c
+		indexLineNames(name, c)
+	}
+
+	if len(chips) == 0 {
+		return errors.New("Error opening GPIO chip: no GPIO chip found")
+	}
+	if _, ok := chips[defaultChip]; !ok {
+		log.Printf("Warning: default GPIO chip %s not found", defaultChip)
+	}
 
 	select {
 	case <-ctx.Done():
-		log.Println("Closing GPIO chip due to context cancellation")
-		_ = c.Close()
+		log.Println("Closing GPIO chips due to context cancellation")
+		for name, c := range chips {
+			_ = c.Close()
+			delete(chips, name)
+		}
+		clear(lineNames)
 		return ctx.Err()
 	default:
-		log.Println("GPIO chip initialized")
+		log.Printf("GPIO: %d chips initialized", len(chips))
 	}
 	return nil
 }
 
+// Initialize opens the GPIO chips and replays the pin states stored in the database.
 func Initialize(ctx context.Context) error {
 	var initErr error
 	once.Do(func() {
-		initErr = initializeChip(ctx)
+		loadInterlocks()
+		if initErr = initializeChips(ctx); initErr != nil {
+			return
+		}
+		report := restorePins()
+		log.Printf("GPIO: restored %d pins, skipped %d, failed %d",
+			len(report.Restored), len(report.Skipped), len(report.Failed))
+		restoreLeases()
 	})
 	return initErr
 }
 
+// indexLineNames records the kernel name of every line of chip in lineNames.
+// The caller must hold mu.
+func indexLineNames(name string, c Chip) {
+	for offset := 0; offset < c.Lines(); offset++ {
+		info, err := c.LineInfo(offset)
+		if err != nil {
+			log.Printf("Error retrieving line info for line %s:%d: %v\n", name, offset, err)
+			continue
+		}
+		if info.Name == "" {
+			continue
+		}
+		lineNames[info.Name] = append(lineNames[info.Name], dto.Line{Chip: name, Offset: offset})
+	}
+}
+
+// LookupLineName returns the line with the given kernel name, searching all chips.
+func LookupLineName(name string) (dto.Line, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	found := lineNames[name]
+	switch len(found) {
+	case 0:
+		return dto.Line{}, fmt.Errorf("%w: %s", ErrUnknownPin, name)
+	case 1:
+		return found[0], nil
+	default:
+		return dto.Line{}, fmt.Errorf("%w: %s is named on %d lines", ErrAmbiguousPin, name, len(found))
+	}
+}
+
 func CheckChip() bool {
 	mu.RLock()
 	defer mu.RUnlock()
-	return Chip != nil
+	return len(chips) > 0
 }
 
-func setPinMode(pin dto.PinMode, asOutput bool) error {
-	mu.Lock()
-	defer mu.Unlock()
+// DefaultLine returns the line at offset on the default chip.
+func DefaultLine(offset int) dto.Line {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dto.Line{Chip: defaultChip, Offset: offset}
+}
 
-	if lines[pin.Pin] != nil {
-		_ = lines[pin.Pin].Close()
+// getChip returns an open chip by name.
+// The caller must hold mu.
+func getChip(name string) (Chip, error) {
+	c, ok := chips[name]
+	if !ok {
+		return nil, fmt.Errorf("GPIO: unknown chip %q", name)
 	}
+	return c, nil
+}
 
-	var l *gpiocdev.Line
-	var err error
+func setPinMode(pin dto.PinMode, asOutput bool, source dto.Source) error {
+	mu.Lock()
+	defer mu.Unlock()
 
-	if asOutput {
-		l, err = Chip.RequestLine(pin.Pin, gpiocdev.AsOutput(pin.Value))
-	} else {
-		l, err = Chip.RequestLine(pin.Pin, gpiocdev.AsInput)
+	off, err := checkInterlocks([]dto.PinMode{pin})
+	if err != nil {
+		return err
 	}
-
+	old := heldMode(pin.Line())
+	l, err := requestLine(pin, asOutput, nil)
 	if err != nil {
-		return fmt.Errorf("GPIO: Error requesting line for pin %d: %w", pin.Pin, err)
+		return err
 	}
-
-	lines[pin.Pin] = l
+	markInactive(off)
+	audit(dto.ChangeSet, old, pin, source, "")
 
 	err = db.SetPin(pin)
 	if err != nil {
@@ -87,42 +170,111 @@ func setPinMode(pin dto.PinMode, asOutput bool) error {
 	if !asOutput {
 		val, err := l.Value()
 		if err != nil {
-			return fmt.Errorf("GPIO: Error reading pin %d value: %w", pin.Pin, err)
+			return fmt.Errorf("GPIO: Error reading pin %s value: %w", pin.Line(), err)
 		}
-		log.Printf("Pin %d value: %d", pin.Pin, val)
+		log.Printf("Pin %s value: %d", pin.Line(), val)
 	}
 
-	log.Printf("GPIO: Pin %d set to %d", pin.Pin, pin.Value)
+	log.Printf("GPIO: Pin %s set to %d", pin.Line(), pin.Value)
 	return nil
 }
 
-func setOutput(pin dto.PinMode) error {
-	return setPinMode(pin, true)
+// requestLine (re)requests the line for pin and stores it in lines, with edge
+// detection if watch is not nil.
+// The caller must hold mu.
+func requestLine(pin dto.PinMode, asOutput bool, watch *LineWatch) (Line, error) {
+	c, err := getChip(pin.Chip)
+	if err != nil {
+		return nil, err
+	}
+	releaseLine(pin.Line())
+
+	l, err := c.RequestLine(pin.Pin, lineConfig(pin, asOutput), watch)
+	if err != nil {
+		return nil, fmt.Errorf("GPIO: Error requesting line for pin %s: %w", pin.Line(), err)
+	}
+
+	lines[pin.Line()] = l
+	modes[pin.Line()] = pin
+	return l, nil
 }
 
-func setInput(pin dto.PinMode) error {
-	return setPinMode(pin, false)
+// lineConfig translates the configuration of pin into the configuration the
+// line is requested with.
+func lineConfig(pin dto.PinMode, asOutput bool) LineConfig {
+	cfg := LineConfig{
+		Output:    asOutput,
+		ActiveLow: pin.Active == dto.Low,
+		Bias:      pin.Bias,
+	}
+	if asOutput {
+		cfg.Value = pin.Value
+		cfg.Drive = pin.Drive
+	}
+	return cfg
 }
 
-func SetBool(pin dto.PinMode) error {
-	if pin.Direction != "in" && pin.Direction != "out" {
-		return errors.New("invalid direction")
-	} else if pin.Direction == "out" {
-		return setOutput(pin)
-	} else {
-		return setInput(pin)
+// applyMode requests the line for pin according to its direction.
+// The caller must hold mu.
+func applyMode(pin dto.PinMode) error {
+	if pin.Direction == dto.PWM {
+		return startPWM(pin)
 	}
+	_, err := requestLine(pin, pin.Direction == dto.Output, nil)
+	return err
 }
 
-func GetAll() (db.Map, error) {
-	if !CheckChip() {
-		return nil, errors.New("GPIO chip not initialized")
+// releaseLine closes the held line, ending any pulse, event subscription or
+// PWM output on it.
+// The caller must hold mu.
+func releaseLine(line dto.Line) {
+	cancelPulse(line)
+	if p, ok := pwms[line]; ok {
+		p.stop()
+		delete(pwms, line)
+	}
+	if w, ok := watches[line]; ok {
+		w.closeSubscribers()
+		delete(watches, line)
+	}
+	switch l := lines[line].(type) {
+	case *batchLine:
+		splitBatch(l.batch, line)
+	case Line:
+		_ = l.Close()
+	}
+	delete(lines, line)
+	delete(modes, line)
+}
+
+func setOutput(pin dto.PinMode, source dto.Source) error {
+	return setPinMode(pin, true, source)
+}
+
+func setInput(pin dto.PinMode, source dto.Source) error {
+	return setPinMode(pin, false, source)
+}
+
+// SetBool configures a pin, source being recorded in the audit log.
+func SetBool(pin dto.PinMode, source dto.Source) error {
+	if pin.Chip == "" {
+		pin.Chip = DefaultLine(pin.Pin).Chip
 	}
 
-	return db.GetAllPin()
+	switch pin.Direction {
+	case dto.Output:
+		return setOutput(pin, source)
+	case dto.Input:
+		return setInput(pin, source)
+	case dto.PWM:
+		return setPWM(pin, source)
+	default:
+		return errors.New("invalid direction")
+	}
 }
 
-func GetGpioAll() (map[int]string, error) {
+// GetGpioAll returns the consumer of every used line, per chip.
+func GetGpioAll() (map[string]map[int]string, error) {
 	if !CheckChip() {
 		return nil, errors.New("GPIO chip not initialized")
 	}
@@ -130,19 +282,21 @@ func GetGpioAll() (map[int]string, error) {
 	mu.RLock()
 	defer mu.RUnlock()
 
-	numLines := Chip.Lines()
-	usedPins := make(map[int]string)
-
-	for offset := 0; offset < numLines; offset++ {
-		info, err := Chip.LineInfo(offset)
-		if err != nil {
-			log.Printf("Error retrieving line info for line %d: %v\n", offset, err)
-			continue
-		}
+	usedPins := make(map[string]map[int]string, len(chips))
+	for name, c := range chips {
+		used := make(map[int]string)
+		for offset := 0; offset < c.Lines(); offset++ {
+			info, err := c.LineInfo(offset)
+			if err != nil {
+				log.Printf("Error retrieving line info for line %s:%d: %v\n", name, offset, err)
+				continue
+			}
 
-		if info.Consumer != "" {
-			usedPins[offset] = info.Consumer
+			if info.Consumer != "" {
+				used[offset] = info.Consumer
+			}
 		}
+		usedPins[name] = used
 	}
 
 	return usedPins, nil
@@ -0,0 +1,143 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// ErrNoPulse is returned when cancelling a pin that has no pulse running.
+var ErrNoPulse = errors.New("no pulse running on pin")
+
+// pulse is a timed pulse or blink pattern running on an output line.
+type pulse struct {
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	finished chan struct{} // closed once the line is back at its resting level
+}
+
+var pulses = make(map[dto.Line]*pulse)
+
+// stop cancels the pulse and waits until the line is back at its resting level.
+func (p *pulse) stop() {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	<-p.finished
+}
+
+// wait sleeps for d, returning false if the pulse was cancelled.
+func (p *pulse) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.stopCh:
+		return false
+	}
+}
+
+func (p *pulse) run(pin dto.Line, l lineHandle, rest int, duration, interval time.Duration, repeat int) {
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if pulses[pin] == p {
+			delete(pulses, pin)
+			if rest == 0 {
+				markInactive([]dto.Line{pin})
+			}
+		}
+	}()
+	// Whatever happens the line goes back to rest before anyone waiting on stop continues.
+	defer close(p.finished)
+	defer func() {
+		if err := l.SetValue(rest); err != nil {
+			log.Printf("GPIO: Error returning pin %s to %d after pulse: %v", pin, rest, err)
+		}
+	}()
+
+	for i := 0; i < repeat; i++ {
+		if i > 0 && !p.wait(interval) {
+			return
+		}
+		if err := l.SetValue(1 - rest); err != nil {
+			log.Printf("GPIO: Error pulsing pin %s: %v", pin, err)
+			return
+		}
+		if !p.wait(duration) {
+			return
+		}
+		if err := l.SetValue(rest); err != nil {
+			log.Printf("GPIO: Error pulsing pin %s: %v", pin, err)
+			return
+		}
+	}
+}
+
+// Pulse drives an output pin away from its resting level for the pulse
+// duration, repeating as requested, and always returns it to rest. A pulse
+// already running on the pin is cancelled first.
+func Pulse(pin dto.Line, req dto.Pulse, source dto.Source) error {
+	if !CheckChip() {
+		return errors.New("GPIO chip not initialized")
+	}
+	duration, interval, err := req.Periods()
+	if err != nil {
+		return err
+	}
+	repeat := req.Repeat
+	if repeat == 0 {
+		repeat = 1
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	mode, held := modes[pin]
+	if !held || mode.Direction != dto.Output {
+		return fmt.Errorf("GPIO: pin %s is not configured as output", pin)
+	}
+	// The pin is active for the pulse when it rests inactive.
+	pulsed := mode
+	pulsed.Value = 1 - mode.Value
+	if _, err := checkInterlocks([]dto.PinMode{pulsed}); err != nil {
+		return err
+	}
+	cancelPulse(pin)
+
+	p := &pulse{stopCh: make(chan struct{}), finished: make(chan struct{})}
+	pulses[pin] = p
+	go p.run(pin, lines[pin], mode.Value, duration, interval, repeat)
+
+	detail := duration.String()
+	if repeat > 1 {
+		detail = fmt.Sprintf("%d times for %s every %s", repeat, duration, interval)
+	}
+	audit(dto.ChangePulse, &mode, mode, source, detail)
+	log.Printf("GPIO: Pin %s pulsing %d times for %s", pin, repeat, duration)
+	return nil
+}
+
+// CancelPulse stops the pulse running on pin, returning it to its resting level.
+func CancelPulse(pin dto.Line) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := pulses[pin]; !ok {
+		return ErrNoPulse
+	}
+	cancelPulse(pin)
+	return nil
+}
+
+// cancelPulse stops the pulse running on pin, if any.
+// The caller must hold mu.
+func cancelPulse(pin dto.Line) {
+	if p, ok := pulses[pin]; ok {
+		p.stop()
+		delete(pulses, pin)
+	}
+}
@@ -0,0 +1,150 @@
+package gpio
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// pwm drives an output line with a software generated PWM signal.
+type pwm struct {
+	pin  dto.Line
+	line Line
+
+	mu        sync.Mutex // guards frequency and dutyCycle
+	frequency float64
+	dutyCycle float64
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+var pwms = make(map[dto.Line]*pwm)
+
+func newPWM(pin dto.Line, line Line, frequency, dutyCycle float64) *pwm {
+	return &pwm{
+		pin:       pin,
+		line:      line,
+		frequency: frequency,
+		dutyCycle: dutyCycle,
+		stopCh:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// set changes the frequency and duty cycle, taking effect on the next period.
+func (p *pwm) set(frequency, dutyCycle float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frequency = frequency
+	p.dutyCycle = dutyCycle
+}
+
+// timing returns the length of a period and how long the line stays high in it.
+func (p *pwm) timing() (period, high time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	period = time.Duration(float64(time.Second) / p.frequency)
+	high = time.Duration(float64(period) * p.dutyCycle / 100)
+	return period, high
+}
+
+func (p *pwm) run() {
+	defer close(p.done)
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	level := -1
+
+	// hold sets the line to value and waits d, returning false once stopped.
+	hold := func(value int, d time.Duration) bool {
+		if value != level {
+			if err := p.line.SetValue(value); err != nil {
+				log.Printf("GPIO: PWM error setting pin %s: %v", p.pin, err)
+			}
+			level = value
+		}
+		timer.Reset(d)
+		select {
+		case <-timer.C:
+			return true
+		case <-p.stopCh:
+			timer.Stop()
+			return false
+		}
+	}
+
+	for {
+		period, high := p.timing()
+		switch {
+		case high <= 0:
+			if !hold(0, period) {
+				return
+			}
+		case high >= period:
+			if !hold(1, period) {
+				return
+			}
+		default:
+			if !hold(1, high) || !hold(0, period-high) {
+				return
+			}
+		}
+	}
+}
+
+// stop ends the PWM output and waits for its goroutine to return.
+func (p *pwm) stop() {
+	close(p.stopCh)
+	<-p.done
+}
+
+// startPWM starts a PWM output on pin, or updates it in place if the line is
+// already running PWM with the same electrical configuration.
+// The caller must hold mu.
+func startPWM(pin dto.PinMode) error {
+	if p, ok := pwms[pin.Line()]; ok {
+		prev := modes[pin.Line()]
+		if prev.Active == pin.Active && prev.Bias == pin.Bias && prev.Drive == pin.Drive {
+			p.set(pin.Frequency, pin.DutyCycle)
+			modes[pin.Line()] = pin
+			return nil
+		}
+	}
+
+	l, err := requestLine(pin, true, nil)
+	if err != nil {
+		return err
+	}
+	p := newPWM(pin.Line(), l, pin.Frequency, pin.DutyCycle)
+	pwms[pin.Line()] = p
+	go p.run()
+	return nil
+}
+
+func setPWM(pin dto.PinMode, source dto.Source) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	off, err := checkInterlocks([]dto.PinMode{pin})
+	if err != nil {
+		return err
+	}
+	old := heldMode(pin.Line())
+	if err := startPWM(pin); err != nil {
+		return err
+	}
+	markInactive(off)
+	audit(dto.ChangeSet, old, pin, source, "")
+
+	if err := db.SetPin(pin); err != nil {
+		return fmt.Errorf("GPIO: Error setting pin value in database: %w", err)
+	}
+
+	log.Printf("GPIO: Pin %s PWM at %.2f Hz, %.1f%% duty cycle", pin.Line(), pin.Frequency, pin.DutyCycle)
+	return nil
+}
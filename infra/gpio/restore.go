@@ -0,0 +1,83 @@
+package gpio
+
+import (
+	"log"
+	"sort"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// RestoreReport describes the outcome of replaying the stored pins on startup.
+// Pins are identified as "chip:offset".
+type RestoreReport struct {
+	Restored []string          `json:"restored"`
+	Skipped  []string          `json:"skipped"`
+	Failed   map[string]string `json:"failed"`
+}
+
+var lastRestore = RestoreReport{Failed: make(map[string]string)}
+
+// restorePins requests every pin stored in the database again, applying its restore policy.
+func restorePins() RestoreReport {
+	report := RestoreReport{Failed: make(map[string]string)}
+
+	pins, err := db.GetPinMap()
+	if err != nil {
+		log.Println("GPIO: no stored pins to restore:", err)
+		setRestoreReport(report)
+		return report
+	}
+
+	keys := make([]string, 0, len(pins))
+	for key := range pins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mu.Lock()
+	for _, key := range keys {
+		pin := pins[key]
+
+		switch pin.Restore {
+		case dto.RestoreNone:
+			report.Skipped = append(report.Skipped, key)
+			continue
+		case dto.RestoreSafe:
+			if pin.Direction == dto.PWM {
+				pin.Direction = dto.Output
+			}
+			pin.Value = pin.SafeValue
+		}
+
+		if _, err := checkInterlocks([]dto.PinMode{pin}); err != nil {
+			log.Printf("GPIO: Pin %s not restored: %v", key, err)
+			report.Failed[key] = err.Error()
+			continue
+		}
+		if err := applyMode(pin); err != nil {
+			log.Println(err)
+			report.Failed[key] = err.Error()
+			continue
+		}
+		audit(dto.ChangeRestore, nil, pin, dto.Source{Type: dto.SourceStartup}, pin.Restore)
+		report.Restored = append(report.Restored, key)
+	}
+	mu.Unlock()
+
+	setRestoreReport(report)
+	return report
+}
+
+func setRestoreReport(report RestoreReport) {
+	mu.Lock()
+	defer mu.Unlock()
+	lastRestore = report
+}
+
+// GetRestoreReport returns the report of the last startup restore.
+func GetRestoreReport() RestoreReport {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lastRestore
+}
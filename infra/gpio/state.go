@@ -0,0 +1,118 @@
+package gpio
+
+import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/rosedblabs/rosedb/v2"
+)
+
+// PinState reports the configured state of a pin next to the level measured on the line.
+type PinState struct {
+	Label      *dto.PinLabel `json:"label,omitempty"`
+	Configured *dto.PinMode  `json:"configured"`      // stored configuration, nil if never stored
+	Held       bool          `json:"held"`            // the line is currently requested by this process
+	Value      *int          `json:"value"`           // measured value, nil if the line is not held
+	Error      string        `json:"error,omitempty"` // error reading the measured value
+	Lease      *LeaseState   `json:"lease,omitempty"` // watchdog of the pin, if any
+}
+
+// GetPin returns the configured and measured state of a line.
+func GetPin(line dto.Line) (PinState, error) {
+	if !CheckChip() {
+		return PinState{}, errors.New("GPIO chip not initialized")
+	}
+
+	var state PinState
+	if label, ok := labelOf(line); ok {
+		state.Label = &label
+	}
+	mode, err := db.GetPin(line)
+	if err == nil {
+		state.Configured = &mode
+	} else if !errors.Is(err, db.ErrPinNotFound) {
+		return PinState{}, err
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if state.Configured == nil && lines[line] == nil {
+		return PinState{}, db.ErrPinNotFound
+	}
+	readState(line, &state)
+	return state, nil
+}
+
+// readState fills the measured part and the watchdog of state for line.
+// The caller must hold mu.
+func readState(line dto.Line, state *PinState) {
+	state.Lease = leaseOf(line)
+	l := lines[line]
+	if l == nil {
+		return
+	}
+	state.Held = true
+	val, err := l.Value()
+	if err != nil {
+		state.Error = err.Error()
+		return
+	}
+	state.Value = &val
+}
+
+// GetAll returns the state of every pin stored in the database or held by the
+// process, keyed by chip and offset.
+func GetAll() (map[string]map[int]PinState, error) {
+	if !CheckChip() {
+		return nil, errors.New("GPIO chip not initialized")
+	}
+
+	stored, err := db.GetPinMap()
+	if err != nil && !errors.Is(err, rosedb.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	labels, err := db.GetLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	states := make(map[dto.Line]PinState, len(stored))
+	for _, mode := range stored {
+		mode := mode
+		states[mode.Line()] = PinState{Configured: &mode}
+	}
+	for line := range lines {
+		if _, ok := states[line]; !ok {
+			states[line] = PinState{}
+		}
+	}
+
+	perChip := make(map[string]map[int]PinState)
+	for line, state := range states {
+		if label, ok := labels[line.String()]; ok {
+			state.Label = &label
+		}
+		readState(line, &state)
+
+		if perChip[line.Chip] == nil {
+			perChip[line.Chip] = make(map[int]PinState)
+		}
+		perChip[line.Chip][line.Offset] = state
+	}
+	return perChip, nil
+}
+
+func labelOf(line dto.Line) (dto.PinLabel, bool) {
+	labels, err := db.GetLabels()
+	if err != nil {
+		return dto.PinLabel{}, false
+	}
+	label, ok := labels[line.String()]
+	return label, ok
+}
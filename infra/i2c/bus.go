@@ -0,0 +1,60 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// Backend names accepted in I2C_BACKEND.
+const (
+	BackendDev = "i2c-dev" // the /dev/i2c-* character devices of the kernel
+	BackendSim = "sim"     // an in-memory bus with simulated devices, for machines without I2C
+)
+
+var (
+	// ErrUnknownBus is returned for bus numbers the backend does not have.
+	ErrUnknownBus = errors.New("unknown I2C bus")
+	// ErrNoAck is returned when no device acknowledges its address.
+	ErrNoAck = errors.New("no device acknowledged the address")
+)
+
+// Bus is an I2C bus. Device drivers only talk to the devices through it, so
+// that they can be run against a FakeBus.
+type Bus interface {
+	// Tx writes w to the device at addr, then reads len(r) bytes from it into
+	// r after a repeated start. Either may be empty, not both.
+	Tx(addr dto.I2CAddr, w, r []byte) error
+}
+
+// Backend gives access to the I2C buses of the machine.
+type Backend interface {
+	// Buses returns the numbers of the buses, sorted.
+	Buses() []int
+	// Open returns the bus number bus.
+	Open(bus int) (Bus, error)
+}
+
+// newBackend returns the backend selected in the configuration.
+func newBackend(cfg *configs.Cfg) (Backend, error) {
+	switch cfg.I2CBackend {
+	case "", BackendDev:
+		return devBackend{}, nil
+	case BackendSim:
+		return newSimBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown I2C_BACKEND %q, use %s or %s", cfg.I2CBackend, BackendDev, BackendSim)
+	}
+}
+
+// readRegs reads len(r) bytes from the registers of a device starting at reg.
+func readRegs(bus Bus, addr dto.I2CAddr, reg byte, r []byte) error {
+	return bus.Tx(addr, []byte{reg}, r)
+}
+
+// writeReg writes value to the register reg of a device.
+func writeReg(bus Bus, addr dto.I2CAddr, reg, value byte) error {
+	return bus.Tx(addr, []byte{reg, value}, nil)
+}
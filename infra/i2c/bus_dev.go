@@ -0,0 +1,99 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// From linux/i2c-dev.h and linux/i2c.h.
+const (
+	ioctlRdwr = 0x0707 // I2C_RDWR, combined transactions
+	msgRead   = 0x0001 // I2C_M_RD
+)
+
+// i2cMsg is struct i2c_msg, whose layout matches on 32 and 64 bits.
+type i2cMsg struct {
+	addr  uint16
+	flags uint16
+	len   uint16
+	buf   uintptr
+}
+
+// i2cRdwrData is struct i2c_rdwr_ioctl_data.
+type i2cRdwrData struct {
+	msgs  uintptr
+	nmsgs uint32
+}
+
+// devBackend uses the i2c-dev character devices, /dev/i2c-<bus>. The
+// i2c-dev module must be loaded, e.g. with dtparam=i2c_arm=on on a Raspberry Pi.
+type devBackend struct{}
+
+func (devBackend) Buses() []int {
+	paths, _ := filepath.Glob("/dev/i2c-*")
+	buses := make([]int, 0, len(paths))
+	for _, path := range paths {
+		if n, err := strconv.Atoi(strings.TrimPrefix(path, "/dev/i2c-")); err == nil {
+			buses = append(buses, n)
+		}
+	}
+	sort.Ints(buses)
+	return buses
+}
+
+func (devBackend) Open(bus int) (Bus, error) {
+	path := fmt.Sprintf("/dev/i2c-%d", bus)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownBus, bus)
+	}
+	return devBus{path: path}, nil
+}
+
+// devBus is a bus of devBackend, whose device file is opened for each
+// transaction so that no descriptor is held between requests.
+type devBus struct {
+	path string
+}
+
+func (b devBus) Tx(addr dto.I2CAddr, w, r []byte) error {
+	f, err := os.OpenFile(b.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	msgs := make([]i2cMsg, 0, 2)
+	if len(w) > 0 {
+		msgs = append(msgs, i2cMsg{addr: uint16(addr), len: uint16(len(w)), buf: uintptr(unsafe.Pointer(&w[0]))})
+	}
+	if len(r) > 0 {
+		msgs = append(msgs, i2cMsg{addr: uint16(addr), flags: msgRead, len: uint16(len(r)), buf: uintptr(unsafe.Pointer(&r[0]))})
+	}
+	if len(msgs) == 0 {
+		return errors.New("empty I2C transaction")
+	}
+	data := i2cRdwrData{msgs: uintptr(unsafe.Pointer(&msgs[0])), nmsgs: uint32(len(msgs))}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlRdwr, uintptr(unsafe.Pointer(&data)))
+	runtime.KeepAlive(w)
+	runtime.KeepAlive(r)
+	runtime.KeepAlive(msgs)
+	switch errno {
+	case 0:
+		return nil
+	case syscall.ENXIO, syscall.EREMOTEIO:
+		return fmt.Errorf("%w %s", ErrNoAck, addr)
+	default:
+		return errno
+	}
+}
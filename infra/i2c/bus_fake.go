@@ -0,0 +1,118 @@
+package i2c
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// FakeDevice is a simulated device of a FakeBus.
+type FakeDevice interface {
+	// Tx handles a transaction addressed to the device, as Bus.Tx.
+	Tx(w, r []byte) error
+}
+
+// FakeBus is an in-memory Bus on which devices are simulated, to run the
+// device drivers without hardware. Transactions to an address without a
+// device fail with ErrNoAck.
+type FakeBus struct {
+	mu      sync.Mutex // serializes the transactions
+	devices map[dto.I2CAddr]FakeDevice
+}
+
+// NewFakeBus returns a bus without devices.
+func NewFakeBus() *FakeBus {
+	return &FakeBus{devices: make(map[dto.I2CAddr]FakeDevice)}
+}
+
+// Attach places device at addr, replacing any device there.
+func (b *FakeBus) Attach(addr dto.I2CAddr, device FakeDevice) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.devices[addr] = device
+}
+
+func (b *FakeBus) Tx(addr dto.I2CAddr, w, r []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d, ok := b.devices[addr]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrNoAck, addr)
+	}
+	return d.Tx(w, r)
+}
+
+// FakeRegisters simulates a device with 256 byte registers: the first byte
+// written selects a register, the next ones are written from it on and reads
+// continue from it, the register pointer incrementing after each byte.
+type FakeRegisters struct {
+	Regs    [256]byte
+	pointer byte
+
+	// OnWrite, if set, is called after a register is written, e.g. to
+	// simulate a measurement started by the write.
+	OnWrite func(regs *FakeRegisters, reg byte)
+}
+
+func (d *FakeRegisters) Tx(w, r []byte) error {
+	if len(w) > 0 {
+		d.pointer = w[0]
+		for _, b := range w[1:] {
+			d.Regs[d.pointer] = b
+			if d.OnWrite != nil {
+				d.OnWrite(d, d.pointer)
+			}
+			d.pointer++
+		}
+	}
+	for i := range r {
+		r[i] = d.Regs[d.pointer]
+		d.pointer++
+	}
+	return nil
+}
+
+// FakeWordRegisters simulates a device with 16-bit registers, such as an
+// INA219: the first byte written selects a register, the next ones are
+// written to it most significant byte first and reads return it the same way.
+type FakeWordRegisters struct {
+	Regs    [256]uint16
+	pointer byte
+}
+
+func (d *FakeWordRegisters) Tx(w, r []byte) error {
+	if len(w) > 0 {
+		d.pointer = w[0]
+		if len(w) >= 3 {
+			d.Regs[d.pointer] = uint16(w[1])<<8 | uint16(w[2])
+		}
+	}
+	for i := range r {
+		r[i] = byte(d.Regs[d.pointer] >> (8 * (1 - i%2)))
+	}
+	return nil
+}
+
+// FakePort simulates a quasi-bidirectional 8-bit port without registers,
+// such as a PCF8574: a byte written sets the latch, a pin reads low if its
+// latch is low or if Inputs pulls it low.
+type FakePort struct {
+	Latch  byte // 0xff on power-up, every pin being an input pulled high
+	Inputs byte // levels applied on the pins from outside
+}
+
+// NewFakePort returns a port in its power-up state with every input high.
+func NewFakePort() *FakePort {
+	return &FakePort{Latch: 0xff, Inputs: 0xff}
+}
+
+func (d *FakePort) Tx(w, r []byte) error {
+	if len(w) > 0 {
+		d.Latch = w[len(w)-1]
+	}
+	for i := range r {
+		r[i] = d.Latch & d.Inputs
+	}
+	return nil
+}
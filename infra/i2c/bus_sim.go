@@ -0,0 +1,66 @@
+package i2c
+
+import (
+	"fmt"
+)
+
+// simBus is the number of the bus of simBackend, the one on the GPIO header
+// of a Raspberry Pi.
+const simBus = 1
+
+// simBackend has a single FakeBus with a BME280 at 0x76, an INA219 at 0x40
+// and a PCF8574 at 0x20, so that the API can be used on machines without I2C.
+type simBackend struct {
+	bus *FakeBus
+}
+
+func newSimBackend() *simBackend {
+	bus := NewFakeBus()
+	bus.Attach(0x76, simBME280())
+	bus.Attach(0x40, simINA219())
+	bus.Attach(0x20, NewFakePort())
+	return &simBackend{bus: bus}
+}
+
+func (b *simBackend) Buses() []int {
+	return []int{simBus}
+}
+
+func (b *simBackend) Open(bus int) (Bus, error) {
+	if bus != simBus {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownBus, bus)
+	}
+	return b.bus, nil
+}
+
+// simBME280 returns a BME280 measuring 25.08 °C and 1006.53 hPa, with the
+// trimming and raw values of the datasheet example, and 56.21 %.
+func simBME280() *FakeRegisters {
+	d := &FakeRegisters{}
+	calib := []int{27504, 26435, -1000, 36477, -10685, 3024, 2855, 140, -7, 15500, -14600, 6000}
+	for i, v := range calib {
+		d.Regs[bme280RegCalib1+2*i] = byte(v)
+		d.Regs[bme280RegCalib1+2*i+1] = byte(v >> 8)
+	}
+	d.Regs[0xa1] = 75 // h1
+	d.Regs[0xe1], d.Regs[0xe2] = 370&0xff, 370>>8
+	d.Regs[0xe3] = 0
+	d.Regs[0xe4], d.Regs[0xe5], d.Regs[0xe6] = 313>>4, 50&0x0f<<4|313&0x0f, 50>>4
+	d.Regs[0xe7] = 30
+	d.Regs[bme280RegChipID] = bme280ChipID
+
+	const adcP, adcT, adcH = 415148, 519888, 30000
+	d.Regs[0xf7], d.Regs[0xf8], d.Regs[0xf9] = adcP>>12, adcP>>4&0xff, adcP&0x0f<<4
+	d.Regs[0xfa], d.Regs[0xfb], d.Regs[0xfc] = adcT>>12, adcT>>4&0xff, adcT&0x0f<<4
+	d.Regs[0xfd], d.Regs[0xfe] = adcH>>8, adcH&0xff
+	return d
+}
+
+// simINA219 returns an INA219 measuring 50 mA at 5 V through a 0.1 Ω shunt.
+func simINA219() *FakeWordRegisters {
+	d := &FakeWordRegisters{}
+	d.Regs[0x00] = 0x399f              // configuration on power-up
+	d.Regs[ina219RegShunt] = 500       // 5 mV
+	d.Regs[ina219RegBus] = 1250<<3 | 2 // 5 V, conversion ready
+	return d
+}
@@ -0,0 +1,104 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// ErrUnknownDriver is returned for device drivers that are not registered.
+var ErrUnknownDriver = errors.New("unknown I2C driver")
+
+// DeviceDriver decodes the readings of a kind of device. Drivers register
+// themselves with RegisterDriver and only reach the device through the Bus
+// they are given, so that they can be run against a FakeBus.
+type DeviceDriver interface {
+	// Addresses returns the addresses the device can be strapped to, the
+	// default one first.
+	Addresses() []dto.I2CAddr
+	// Options returns the names of the options the driver accepts.
+	Options() []string
+	// Read returns the current readings of the device at addr.
+	Read(bus Bus, addr dto.I2CAddr, options map[string]float64) ([]dto.I2CReading, error)
+}
+
+// DriverInfo describes a registered device driver.
+type DriverInfo struct {
+	Name      string        `json:"name"`
+	Addresses []dto.I2CAddr `json:"addresses"`
+	Options   []string      `json:"options,omitempty"`
+}
+
+// drivers are the registered device drivers keyed by name, filled by the
+// init functions of the driver files.
+var drivers = make(map[string]DeviceDriver)
+
+// RegisterDriver makes a device driver available under name.
+func RegisterDriver(name string, driver DeviceDriver) {
+	if _, ok := drivers[name]; ok {
+		panic("i2c: driver registered twice: " + name)
+	}
+	drivers[name] = driver
+}
+
+// LookupDriver returns the device driver registered under name, e.g. to run
+// it against a FakeBus.
+func LookupDriver(name string) (DeviceDriver, bool) {
+	d, ok := drivers[name]
+	return d, ok
+}
+
+// Drivers returns the registered device drivers, sorted by name.
+func Drivers() []DriverInfo {
+	list := make([]DriverInfo, 0, len(drivers))
+	for name, d := range drivers {
+		list = append(list, DriverInfo{Name: name, Addresses: d.Addresses(), Options: d.Options()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// checkDevice checks the driver and options of device and returns it with
+// the default address of the driver if it has none.
+func checkDevice(device dto.I2CDevice) (dto.I2CDevice, error) {
+	d, ok := drivers[device.Driver]
+	if !ok {
+		names := make([]string, 0, len(drivers))
+		for name := range drivers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return device, fmt.Errorf("%w %q, use one of %s", ErrUnknownDriver, device.Driver, strings.Join(names, ", "))
+	}
+	for option := range device.Options {
+		known := false
+		for _, o := range d.Options() {
+			known = known || o == option
+		}
+		if !known {
+			return device, fmt.Errorf("invalid option %q for driver %s", option, device.Driver)
+		}
+	}
+	if device.Address == 0 {
+		device.Address = d.Addresses()[0]
+	}
+	return device, nil
+}
+
+// option returns the option name of options, or def if it is not set.
+func option(options map[string]float64, name string, def float64) float64 {
+	if v, ok := options[name]; ok {
+		return v
+	}
+	return def
+}
+
+// round rounds v to digits decimals, below the resolution of the sensors.
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
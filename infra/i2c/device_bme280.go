@@ -0,0 +1,172 @@
+package i2c
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// Registers of the BME280 and BMP280.
+const (
+	bme280RegCalib1   = 0x88 // 26 bytes of calibration, temperature and pressure, then dig_H1
+	bme280RegChipID   = 0xd0
+	bme280RegCalib2   = 0xe1 // 7 bytes of humidity calibration, BME280 only
+	bme280RegCtrlHum  = 0xf2
+	bme280RegStatus   = 0xf3
+	bme280RegCtrlMeas = 0xf4
+	bme280RegData     = 0xf7 // pressure, temperature and humidity, 8 bytes
+
+	bme280ChipID = 0x60
+	bmp280ChipID = 0x58 // same sensor without humidity
+
+	bme280Measuring = 0x08 // bit of the status register
+	bme280Forced    = 0x25 // ctrl_meas: temperature and pressure oversampling x1, forced mode
+)
+
+func init() {
+	RegisterDriver("bme280", bme280{})
+}
+
+// bme280 reads the temperature, pressure and, on a BME280, humidity of a
+// Bosch BME280 or BMP280. Each reading triggers a single measurement in
+// forced mode, the sensor sleeping in between.
+type bme280 struct{}
+
+func (bme280) Addresses() []dto.I2CAddr { return []dto.I2CAddr{0x76, 0x77} }
+
+func (bme280) Options() []string { return nil }
+
+func (bme280) Read(bus Bus, addr dto.I2CAddr, _ map[string]float64) ([]dto.I2CReading, error) {
+	id := make([]byte, 1)
+	if err := readRegs(bus, addr, bme280RegChipID, id); err != nil {
+		return nil, err
+	}
+	humidity := id[0] == bme280ChipID
+	if !humidity && id[0] != bmp280ChipID {
+		return nil, fmt.Errorf("unexpected chip id 0x%02x, not a BME280 or BMP280", id[0])
+	}
+
+	calib := make([]byte, 26)
+	if err := readRegs(bus, addr, bme280RegCalib1, calib); err != nil {
+		return nil, err
+	}
+	c := parseBME280Calib(calib)
+	if humidity {
+		calib2 := make([]byte, 7)
+		if err := readRegs(bus, addr, bme280RegCalib2, calib2); err != nil {
+			return nil, err
+		}
+		c.parseHumidity(calib[25], calib2)
+		// ctrl_hum only takes effect once ctrl_meas is written.
+		if err := writeReg(bus, addr, bme280RegCtrlHum, 0x01); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeReg(bus, addr, bme280RegCtrlMeas, bme280Forced); err != nil {
+		return nil, err
+	}
+	if err := bme280Wait(bus, addr); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 8)
+	if err := readRegs(bus, addr, bme280RegData, data); err != nil {
+		return nil, err
+	}
+	adcP := float64(uint32(data[0])<<12 | uint32(data[1])<<4 | uint32(data[2])>>4)
+	adcT := float64(uint32(data[3])<<12 | uint32(data[4])<<4 | uint32(data[5])>>4)
+	adcH := float64(uint16(data[6])<<8 | uint16(data[7]))
+
+	temperature, tFine := c.temperature(adcT)
+	readings := []dto.I2CReading{
+		{Name: "temperature", Value: round(temperature, 2), Unit: "°C"},
+		{Name: "pressure", Value: round(c.pressure(adcP, tFine)/100, 2), Unit: "hPa"},
+	}
+	if humidity {
+		readings = append(readings, dto.I2CReading{Name: "humidity", Value: round(c.humidity(adcH, tFine), 2), Unit: "%"})
+	}
+	return readings, nil
+}
+
+// bme280Wait waits for the end of the measurement, which takes about 10 ms
+// with the oversampling used.
+func bme280Wait(bus Bus, addr dto.I2CAddr) error {
+	status := make([]byte, 1)
+	for i := 0; i < 20; i++ {
+		time.Sleep(5 * time.Millisecond)
+		if err := readRegs(bus, addr, bme280RegStatus, status); err != nil {
+			return err
+		}
+		if status[0]&bme280Measuring == 0 {
+			return nil
+		}
+	}
+	return errors.New("timeout waiting for the measurement")
+}
+
+// bme280Calib is the trimming of a sensor, stored in its memory.
+type bme280Calib struct {
+	t1, t2, t3                         float64
+	p1, p2, p3, p4, p5, p6, p7, p8, p9 float64
+	h1, h2, h3, h4, h5, h6             float64
+}
+
+func parseBME280Calib(b []byte) bme280Calib {
+	u16 := func(i int) float64 { return float64(binary.LittleEndian.Uint16(b[i:])) }
+	s16 := func(i int) float64 { return float64(int16(binary.LittleEndian.Uint16(b[i:]))) }
+	return bme280Calib{
+		t1: u16(0), t2: s16(2), t3: s16(4),
+		p1: u16(6), p2: s16(8), p3: s16(10), p4: s16(12), p5: s16(14),
+		p6: s16(16), p7: s16(18), p8: s16(20), p9: s16(22),
+	}
+}
+
+// parseHumidity reads the humidity trimming, h1 and the registers from 0xe1.
+func (c *bme280Calib) parseHumidity(h1 byte, b []byte) {
+	c.h1 = float64(h1)
+	c.h2 = float64(int16(binary.LittleEndian.Uint16(b[0:])))
+	c.h3 = float64(b[2])
+	// h4 and h5 are signed 12-bit values sharing the register 0xe5.
+	c.h4 = float64(int16(int8(b[3]))<<4 | int16(b[4]&0x0f))
+	c.h5 = float64(int16(int8(b[5]))<<4 | int16(b[4]>>4))
+	c.h6 = float64(int8(b[6]))
+}
+
+// The compensation formulas below are the floating point ones of the BME280
+// datasheet. t_fine carries the temperature to the other two.
+
+func (c *bme280Calib) temperature(adc float64) (float64, float64) {
+	v1 := (adc/16384 - c.t1/1024) * c.t2
+	v2 := (adc/131072 - c.t1/8192) * (adc/131072 - c.t1/8192) * c.t3
+	tFine := v1 + v2
+	return tFine / 5120, tFine
+}
+
+// pressure returns the pressure in Pa.
+func (c *bme280Calib) pressure(adc, tFine float64) float64 {
+	v1 := tFine/2 - 64000
+	v2 := v1 * v1 * c.p6 / 32768
+	v2 += v1 * c.p5 * 2
+	v2 = v2/4 + c.p4*65536
+	v1 = (c.p3*v1*v1/524288 + c.p2*v1) / 524288
+	v1 = (1 + v1/32768) * c.p1
+	if v1 == 0 {
+		return 0
+	}
+	p := 1048576 - adc
+	p = (p - v2/4096) * 6250 / v1
+	v1 = c.p9 * p * p / 2147483648
+	v2 = p * c.p8 / 32768
+	return p + (v1+v2+c.p7)/16
+}
+
+// humidity returns the relative humidity in percent.
+func (c *bme280Calib) humidity(adc, tFine float64) float64 {
+	h := tFine - 76800
+	h = (adc - (c.h4*64 + c.h5/16384*h)) * (c.h2 / 65536 * (1 + c.h6/67108864*h*(1+c.h3/67108864*h)))
+	h *= 1 - c.h1*h/524288
+	return min(max(h, 0), 100)
+}
@@ -0,0 +1,64 @@
+package i2c
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// Registers of the INA219.
+const (
+	ina219RegShunt = 0x01 // shunt voltage, signed, 10 µV per bit
+	ina219RegBus   = 0x02 // bus voltage in bits 15-3, 4 mV per bit
+
+	ina219Overflow = 0x01 // bit of the bus voltage register set when the current is out of range
+)
+
+func init() {
+	RegisterDriver("ina219", ina219{})
+}
+
+// ina219 reads the bus voltage and the current of a TI INA219. The current
+// is computed from the shunt voltage and the shunt_ohms option (0.1 Ω, as on
+// most breakout boards, by default), so the calibration register is left alone.
+type ina219 struct{}
+
+func (ina219) Addresses() []dto.I2CAddr {
+	addrs := make([]dto.I2CAddr, 0, 16)
+	for a := dto.I2CAddr(0x40); a <= 0x4f; a++ {
+		addrs = append(addrs, a)
+	}
+	return addrs
+}
+
+func (ina219) Options() []string { return []string{"shunt_ohms"} }
+
+func (ina219) Read(bus Bus, addr dto.I2CAddr, options map[string]float64) ([]dto.I2CReading, error) {
+	shunt := option(options, "shunt_ohms", 0.1)
+	if shunt <= 0 {
+		return nil, errors.New("invalid shunt_ohms, it must be positive")
+	}
+
+	b := make([]byte, 2)
+	if err := readRegs(bus, addr, ina219RegShunt, b); err != nil {
+		return nil, err
+	}
+	shuntVolts := float64(int16(binary.BigEndian.Uint16(b))) * 10e-6
+	if err := readRegs(bus, addr, ina219RegBus, b); err != nil {
+		return nil, err
+	}
+	raw := binary.BigEndian.Uint16(b)
+	if raw&ina219Overflow != 0 {
+		return nil, errors.New("current out of range of the shunt")
+	}
+	busVolts := float64(raw>>3) * 4e-3
+	current := shuntVolts / shunt
+
+	return []dto.I2CReading{
+		{Name: "bus_voltage", Value: round(busVolts, 3), Unit: "V"},
+		{Name: "shunt_voltage", Value: round(shuntVolts*1000, 2), Unit: "mV"},
+		{Name: "current", Value: round(current, 4), Unit: "A"},
+		{Name: "power", Value: round(busVolts*current, 4), Unit: "W"},
+	}, nil
+}
@@ -0,0 +1,40 @@
+package i2c
+
+import (
+	"fmt"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+func init() {
+	RegisterDriver("pcf8574", pcf8574{})
+}
+
+// pcf8574 reads the levels of the 8 pins of an NXP PCF8574 or PCF8574A
+// expander. The pins are set by writing the port with a raw transfer.
+type pcf8574 struct{}
+
+func (pcf8574) Addresses() []dto.I2CAddr {
+	addrs := make([]dto.I2CAddr, 0, 16)
+	for a := dto.I2CAddr(0x20); a <= 0x27; a++ {
+		addrs = append(addrs, a)
+	}
+	for a := dto.I2CAddr(0x38); a <= 0x3f; a++ { // PCF8574A
+		addrs = append(addrs, a)
+	}
+	return addrs
+}
+
+func (pcf8574) Options() []string { return nil }
+
+func (pcf8574) Read(bus Bus, addr dto.I2CAddr, _ map[string]float64) ([]dto.I2CReading, error) {
+	port := make([]byte, 1)
+	if err := bus.Tx(addr, nil, port); err != nil {
+		return nil, err
+	}
+	readings := make([]dto.I2CReading, 8)
+	for i := range readings {
+		readings[i] = dto.I2CReading{Name: fmt.Sprintf("p%d", i), Value: float64(port[0] >> i & 1)}
+	}
+	return readings, nil
+}
@@ -0,0 +1,203 @@
+package i2c
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// readDevice reads the device attached at addr of a FakeBus with driver.
+func readDevice(t *testing.T, driver string, addr dto.I2CAddr, device FakeDevice, options map[string]float64) ([]dto.I2CReading, error) {
+	t.Helper()
+	d, ok := LookupDriver(driver)
+	if !ok {
+		t.Fatalf("driver %s is not registered", driver)
+	}
+	bus := NewFakeBus()
+	bus.Attach(addr, device)
+	return d.Read(bus, addr, options)
+}
+
+func checkReadings(t *testing.T, got, want []dto.I2CReading) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d readings %+v, want %+v", len(got), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("reading %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBME280(t *testing.T) {
+	// The trimming and raw values of the example of the datasheet, section
+	// 8.1, compensate to 25.08 °C and 100653.27 Pa.
+	d := simBME280()
+	readings, err := readDevice(t, "bme280", 0x76, d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReadings(t, readings, []dto.I2CReading{
+		{Name: "temperature", Value: 25.08, Unit: "°C"},
+		{Name: "pressure", Value: 1006.53, Unit: "hPa"},
+		{Name: "humidity", Value: 56.21, Unit: "%"},
+	})
+	if d.Regs[bme280RegCtrlHum] != 0x01 || d.Regs[bme280RegCtrlMeas] != bme280Forced {
+		t.Errorf("ctrl_hum = 0x%02x, ctrl_meas = 0x%02x, want 0x01 and 0x%02x",
+			d.Regs[bme280RegCtrlHum], d.Regs[bme280RegCtrlMeas], bme280Forced)
+	}
+
+	// A BMP280 has the same registers but no humidity.
+	d = simBME280()
+	d.Regs[bme280RegChipID] = bmp280ChipID
+	readings, err = readDevice(t, "bme280", 0x77, d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReadings(t, readings, []dto.I2CReading{
+		{Name: "temperature", Value: 25.08, Unit: "°C"},
+		{Name: "pressure", Value: 1006.53, Unit: "hPa"},
+	})
+	if d.Regs[bme280RegCtrlHum] != 0 {
+		t.Errorf("ctrl_hum of a BMP280 written with 0x%02x", d.Regs[bme280RegCtrlHum])
+	}
+}
+
+func TestBME280Errors(t *testing.T) {
+	d := simBME280()
+	d.Regs[bme280RegChipID] = 0x55
+	if _, err := readDevice(t, "bme280", 0x76, d, nil); err == nil {
+		t.Error("a chip id other than a BME280 or BMP280 was read")
+	}
+
+	d = simBME280()
+	d.Regs[bme280RegStatus] = bme280Measuring
+	if _, err := readDevice(t, "bme280", 0x76, d, nil); err == nil {
+		t.Error("a measurement never ending was read")
+	}
+
+	driver, _ := LookupDriver("bme280")
+	if _, err := driver.Read(NewFakeBus(), 0x76, nil); !errors.Is(err, ErrNoAck) {
+		t.Errorf("error = %v, want ErrNoAck", err)
+	}
+}
+
+func TestBME280HumidityCalib(t *testing.T) {
+	// h4 and h5 are signed 12-bit values: -20 is 0xfec and -3 is 0xffd.
+	var c bme280Calib
+	c.parseHumidity(75, []byte{0x72, 0x01, 0x00, 0xfe, 0xdc, 0xff, 0xe2})
+	want := bme280Calib{h1: 75, h2: 370, h3: 0, h4: -20, h5: -3, h6: -30}
+	if c != want {
+		t.Errorf("calibration = %+v, want %+v", c, want)
+	}
+
+	c.parseHumidity(75, []byte{0x72, 0x01, 0x00, 0x13, 0x29, 0x03, 0x1e})
+	want = bme280Calib{h1: 75, h2: 370, h3: 0, h4: 313, h5: 50, h6: 30}
+	if c != want {
+		t.Errorf("calibration = %+v, want %+v", c, want)
+	}
+}
+
+func TestINA219(t *testing.T) {
+	tests := []struct {
+		name    string
+		shunt   uint16 // raw shunt voltage register
+		bus     uint16 // raw bus voltage register
+		options map[string]float64
+		want    []dto.I2CReading
+	}{
+		{
+			name:  "positive current",
+			shunt: 500, // 5 mV
+			bus:   1250 << 3,
+			want: []dto.I2CReading{
+				{Name: "bus_voltage", Value: 5, Unit: "V"},
+				{Name: "shunt_voltage", Value: 5, Unit: "mV"},
+				{Name: "current", Value: 0.05, Unit: "A"},
+				{Name: "power", Value: 0.25, Unit: "W"},
+			},
+		},
+		{
+			name:  "negative current",
+			shunt: 0xfe0c, // -500, -5 mV in two's complement
+			bus:   1250<<3 | 2,
+			want: []dto.I2CReading{
+				{Name: "bus_voltage", Value: 5, Unit: "V"},
+				{Name: "shunt_voltage", Value: -5, Unit: "mV"},
+				{Name: "current", Value: -0.05, Unit: "A"},
+				{Name: "power", Value: -0.25, Unit: "W"},
+			},
+		},
+		{
+			name:  "full scale negative",
+			shunt: 0x8300, // -32000, -320 mV
+			bus:   3000 << 3,
+			want: []dto.I2CReading{
+				{Name: "bus_voltage", Value: 12, Unit: "V"},
+				{Name: "shunt_voltage", Value: -320, Unit: "mV"},
+				{Name: "current", Value: -3.2, Unit: "A"},
+				{Name: "power", Value: -38.4, Unit: "W"},
+			},
+		},
+		{
+			name:    "shunt_ohms",
+			shunt:   500,
+			bus:     825 << 3, // 3.3 V
+			options: map[string]float64{"shunt_ohms": 0.01},
+			want: []dto.I2CReading{
+				{Name: "bus_voltage", Value: 3.3, Unit: "V"},
+				{Name: "shunt_voltage", Value: 5, Unit: "mV"},
+				{Name: "current", Value: 0.5, Unit: "A"},
+				{Name: "power", Value: 1.65, Unit: "W"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &FakeWordRegisters{}
+			d.Regs[ina219RegShunt], d.Regs[ina219RegBus] = tt.shunt, tt.bus
+			readings, err := readDevice(t, "ina219", 0x40, d, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkReadings(t, readings, tt.want)
+		})
+	}
+}
+
+func TestINA219Errors(t *testing.T) {
+	d := simINA219()
+	d.Regs[ina219RegBus] |= ina219Overflow
+	if _, err := readDevice(t, "ina219", 0x40, d, nil); err == nil {
+		t.Error("an overflow was not reported")
+	}
+
+	for _, ohms := range []float64{0, -0.1} {
+		if _, err := readDevice(t, "ina219", 0x40, simINA219(), map[string]float64{"shunt_ohms": ohms}); err == nil {
+			t.Errorf("shunt_ohms %g was accepted", ohms)
+		}
+	}
+}
+
+func TestPCF8574(t *testing.T) {
+	d := NewFakePort()
+	d.Latch = 0x0f  // p4 to p7 driven low
+	d.Inputs = 0xfd // p1 pulled low from outside
+	readings, err := readDevice(t, "pcf8574", 0x20, d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReadings(t, readings, []dto.I2CReading{
+		{Name: "p0", Value: 1},
+		{Name: "p1", Value: 0},
+		{Name: "p2", Value: 1},
+		{Name: "p3", Value: 1},
+		{Name: "p4", Value: 0},
+		{Name: "p5", Value: 0},
+		{Name: "p6", Value: 0},
+		{Name: "p7", Value: 0},
+	})
+}
@@ -0,0 +1,234 @@
+// Package i2c gives access to the I2C buses: scans, raw register transfers
+// and the readings of devices decoded by pluggable drivers.
+package i2c
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// ErrInvalidDevice is returned when a device has an unknown driver or option, or is on an unknown bus.
+var ErrInvalidDevice = errors.New("invalid I2C device")
+
+var (
+	backend Backend
+	mu      sync.Mutex // serializes the transactions, so that a driver reads a device undisturbed
+	devMu   sync.Mutex // guards the devices in the database
+	once    sync.Once
+)
+
+// DeviceStatus is a device together with its current readings.
+type DeviceStatus struct {
+	dto.I2CDevice
+	Readings []dto.I2CReading `json:"readings"`
+	Error    string           `json:"error,omitempty"` // error reading the device
+}
+
+// Initialize opens the backend of I2C_BACKEND.
+func Initialize(ctx context.Context) error {
+	var initErr error
+	once.Do(func() {
+		b, err := newBackend(configs.Conf)
+		if err != nil {
+			initErr = err
+			return
+		}
+		if ctx.Err() != nil {
+			initErr = ctx.Err()
+			return
+		}
+
+		mu.Lock()
+		backend = b
+		mu.Unlock()
+		log.Printf("I2C: %d buses, %d drivers", len(b.Buses()), len(drivers))
+	})
+	return initErr
+}
+
+// CheckBackend reports whether the I2C subsystem is initialized.
+func CheckBackend() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return backend != nil
+}
+
+// Buses returns the numbers of the I2C buses.
+func Buses() ([]int, error) {
+	if !CheckBackend() {
+		return nil, errors.New("I2C not initialized")
+	}
+	return backend.Buses(), nil
+}
+
+// open returns the bus number bus.
+// The caller must hold mu.
+func open(bus int) (Bus, error) {
+	if backend == nil {
+		return nil, errors.New("I2C not initialized")
+	}
+	return backend.Open(bus)
+}
+
+// Scan returns the addresses acknowledged on bus, probing each one with a
+// single byte read like i2cdetect -r.
+func Scan(bus int) ([]dto.I2CAddr, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	b, err := open(bus)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]dto.I2CAddr, 0)
+	r := make([]byte, 1)
+	for addr := dto.I2CAddrFirst; addr <= dto.I2CAddrLast; addr++ {
+		err := b.Tx(addr, nil, r)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, err // the bus itself cannot be opened
+		} else if err == nil {
+			found = append(found, addr)
+		}
+	}
+	return found, nil
+}
+
+// Transfer runs a raw transaction with the device at addr on bus and returns
+// the bytes read.
+func Transfer(bus int, addr dto.I2CAddr, t dto.I2CTransfer) ([]int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	b, err := open(bus)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]byte, t.Read)
+	if err := b.Tx(addr, t.Bytes(), r); err != nil {
+		return nil, err
+	}
+	read := make([]int, len(r))
+	for i, v := range r {
+		read[i] = int(v)
+	}
+	return read, nil
+}
+
+// read returns device with its current readings.
+func read(device dto.I2CDevice) DeviceStatus {
+	s := DeviceStatus{I2CDevice: device, Readings: []dto.I2CReading{}}
+	d, ok := drivers[device.Driver]
+	if !ok {
+		s.Error = fmt.Sprintf("%v %q", ErrUnknownDriver, device.Driver)
+		return s
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	b, err := open(device.Bus)
+	if err == nil {
+		var readings []dto.I2CReading
+		if readings, err = d.Read(b, device.Address, device.Options); err == nil {
+			s.Readings = readings
+		}
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// ListDevices returns every device with its readings, sorted by name.
+func ListDevices() ([]DeviceStatus, error) {
+	devMu.Lock()
+	stored, err := db.GetI2CDevices()
+	devMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]DeviceStatus, 0, len(stored))
+	for _, device := range stored {
+		list = append(list, read(device))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// GetDevice returns a device with its readings.
+func GetDevice(name string) (DeviceStatus, error) {
+	devMu.Lock()
+	stored, err := db.GetI2CDevices()
+	devMu.Unlock()
+	if err != nil {
+		return DeviceStatus{}, err
+	}
+	device, ok := stored[name]
+	if !ok {
+		return DeviceStatus{}, db.ErrI2CDeviceNotFound
+	}
+	return read(device), nil
+}
+
+// SetDevice creates or replaces a device and returns it with its readings.
+// The device is stored even if it does not answer, e.g. while unplugged.
+func SetDevice(device dto.I2CDevice) (DeviceStatus, error) {
+	device, err := checkDevice(device)
+	if err != nil {
+		return DeviceStatus{}, fmt.Errorf("%w: %w", ErrInvalidDevice, err)
+	}
+	buses, err := Buses()
+	if err != nil {
+		return DeviceStatus{}, err
+	}
+	if len(buses) == 0 {
+		return DeviceStatus{}, fmt.Errorf("%w: %w %d, no bus found (is i2c-dev loaded?)", ErrInvalidDevice, ErrUnknownBus, device.Bus)
+	}
+	if !containsBus(buses, device.Bus) {
+		return DeviceStatus{}, fmt.Errorf("%w: %w %d, use one of %s", ErrInvalidDevice, ErrUnknownBus, device.Bus, joinBuses(buses))
+	}
+
+	devMu.Lock()
+	err = db.SetI2CDevice(device)
+	devMu.Unlock()
+	if err != nil {
+		return DeviceStatus{}, err
+	}
+	return read(device), nil
+}
+
+// DeleteDevice removes a device.
+func DeleteDevice(name string) error {
+	devMu.Lock()
+	defer devMu.Unlock()
+	return db.DeleteI2CDevice(name)
+}
+
+func containsBus(buses []int, bus int) bool {
+	for _, b := range buses {
+		if b == bus {
+			return true
+		}
+	}
+	return false
+}
+
+func joinBuses(buses []int) string {
+	names := make([]string, len(buses))
+	for i, b := range buses {
+		names[i] = strconv.Itoa(b)
+	}
+	return strings.Join(names, ", ")
+}
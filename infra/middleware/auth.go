@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/auth"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Require godoc
+// @description Middleware requiring a token that grants scope. Read scopes are
+// only enforced when AUTH_READ is set. The token is sent as "Authorization:
+// Bearer <token>", or as the token query parameter for clients such as
+// EventSource and WebSocket that cannot set headers.
+func Require(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if dto.IsReadScope(scope) && !configs.Conf.AuthRead {
+			return c.Next()
+		}
+
+		secret := bearerToken(c)
+		if secret == "" {
+			return Unauthorized(c, "missing bearer token")
+		}
+
+		token, err := auth.Verify(secret)
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) {
+			return Unauthorized(c, err.Error())
+		} else if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if !token.Allows(scope) {
+			return Forbidden(c, "token lacks scope "+scope)
+		}
+
+		c.Locals("token", token.Name)
+		c.Locals("scopes", token.Scopes)
+		return c.Next()
+	}
+}
+
+// Granted reports whether the token of a request that passed Require grants
+// scope, for handlers whose requirements depend on the body.
+func Granted(c *fiber.Ctx, scope string) bool {
+	scopes, _ := c.Locals("scopes").([]string)
+	token := dto.Token{Scopes: scopes}
+	return token.Allows(scope)
+}
+
+// bearerToken returns the token sent with the request, if any.
+func bearerToken(c *fiber.Ctx) string {
+	if header := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return c.Query("token")
+}
+
+// Unauthorized responds with 401 when no valid credentials were sent.
+func Unauthorized(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error":   "Unauthorized",
+		"message": message,
+	})
+}
+
+// Forbidden responds with 403 when the credentials do not grant access.
+func Forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":   "Forbidden",
+		"message": message,
+	})
+}
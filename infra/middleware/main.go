@@ -1,8 +1,8 @@
 package middleware
 
 import (
-	"github.com/gabrielmoura/raspController/configs"
 	"log"
+	"net/url"
 	"time"
 
 	"github.com/gabrielmoura/raspController/infra/db"
@@ -17,10 +17,13 @@ func CacheMiddleware(ttl int) fiber.Handler {
 			return c.Next()
 		}
 
-		url := c.OriginalURL()
+		key, ok := cacheKey(c)
+		if !ok {
+			return c.Next()
+		}
 
 		// Attempts to retrieve response from storage
-		cachedBody, err := db.DB.Get([]byte(url))
+		cachedBody, err := db.DB.Get([]byte(key))
 		if err == nil {
 			return c.Send(cachedBody)
 		}
@@ -34,22 +37,26 @@ func CacheMiddleware(ttl int) fiber.Handler {
 		body := c.Response().Body()
 
 		// Stores the response in storage
-		err = db.DB.PutWithTTL([]byte(url), body, time.Second*time.Duration(ttl))
+		err = db.DB.PutWithTTL([]byte(key), body, time.Second*time.Duration(ttl))
 		if err != nil {
-			log.Println("Error storing cache for", url, err)
+			log.Println("Error storing cache for", key, err)
 		}
 
 		return nil
 	}
 }
 
-// CheckAuth godoc
-// @description Middleware for authentication
-func CheckAuth(c *fiber.Ctx) error {
-	if c.Get("Authorization") != "Bearer "+configs.Conf.AuthToken {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+// cacheKey returns the path and query of the request without the token
+// parameter, so that access tokens are never written to the database. A
+// malformed query is not cached.
+func cacheKey(c *fiber.Ctx) (string, bool) {
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return "", false
+	}
+	query.Del("token")
+	if len(query) == 0 {
+		return c.Path(), true
 	}
-	return c.Next()
+	return c.Path() + "?" + query.Encode(), true
 }
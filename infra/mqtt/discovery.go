@@ -0,0 +1,92 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+// sensor describes how Home Assistant shows a metric.
+type sensor struct {
+	unit  string
+	class string // device_class, empty for none
+}
+
+var sensors = map[string]sensor{
+	vchiq.MetricCPUTemp:   {"°C", "temperature"},
+	vchiq.MetricGPUTemp:   {"°C", "temperature"},
+	vchiq.MetricCoreVolt:  {"V", "voltage"},
+	vchiq.MetricCPUFreq:   {"MHz", "frequency"},
+	vchiq.MetricLoad1:     {"", ""},
+	vchiq.MetricMemUsed:   {"%", ""},
+	vchiq.MetricDiskRoot:  {"%", ""},
+	vchiq.MetricDiskBoot:  {"%", ""},
+	vchiq.MetricDiskHome:  {"%", ""},
+	vchiq.MetricThrottled: {"", ""},
+}
+
+// discovery reports whether the Home Assistant discovery payloads are published.
+func discovery() bool {
+	return configs.Conf.MQTTDiscovery
+}
+
+// device is the Home Assistant device every entity belongs to.
+func device() map[string]any {
+	hostname, _ := os.Hostname()
+	d := map[string]any{
+		"identifiers":  []string{node},
+		"name":         hostname,
+		"manufacturer": "Raspberry Pi",
+	}
+	if model, err := vchiq.GetDeviceName(); err == nil {
+		d["model"] = model
+	}
+	return d
+}
+
+// config returns the topic and payload announcing an entity to Home Assistant.
+func config(component, object string, payload map[string]any) (string, string) {
+	payload["availability_topic"] = base + "/" + statusTopic
+	payload["device"] = device()
+	body, _ := json.Marshal(payload)
+	return fmt.Sprintf("%s/%s/%s/%s/config", configs.Conf.MQTTDiscoveryPrefix, component, node, object), string(body)
+}
+
+// pinDiscovery announces a labeled pin: a switch for an output, a binary
+// sensor for an input.
+func pinDiscovery(line dto.Line, direction, label string) (string, string) {
+	object := slug(fmt.Sprintf("%s_%d", line.Chip, line.Offset))
+	payload := map[string]any{
+		"name":        label,
+		"unique_id":   node + "_" + object,
+		"state_topic": pinTopic(line) + "/state",
+		"payload_on":  "ON",
+		"payload_off": "OFF",
+	}
+	if direction == dto.Output {
+		payload["command_topic"] = pinTopic(line) + "/set"
+		return config("switch", object, payload)
+	}
+	return config("binary_sensor", object, payload)
+}
+
+// metricDiscovery announces a metric as a sensor.
+func metricDiscovery(metric string) (string, string) {
+	payload := map[string]any{
+		"name":        metric,
+		"unique_id":   node + "_" + metric,
+		"state_topic": base + "/" + telemetryTopic + "/" + metric,
+		"state_class": "measurement",
+	}
+	if s := sensors[metric]; s.unit != "" {
+		payload["unit_of_measurement"] = s.unit
+		if s.class != "" {
+			payload["device_class"] = s.class
+		}
+	}
+	return config("sensor", metric, payload)
+}
@@ -0,0 +1,203 @@
+// Package mqtt bridges the controller to an MQTT broker: the telemetry and the
+// pin states are published, output pins are set from command topics and Home
+// Assistant discovers the labeled pins.
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gabrielmoura/raspController/configs"
+)
+
+// Topics below the base topic.
+const (
+	statusTopic    = "status"    // online or offline, retained
+	telemetryTopic = "telemetry" // telemetry/<metric>
+	gpioTopic      = "gpio"      // gpio/<chip>/<pin>/state and gpio/<chip>/<pin>/set
+)
+
+const (
+	qos          = 1
+	pinsInterval = time.Second // interval between two readings of the pin states
+)
+
+var (
+	client  paho.Client
+	base    string // base topic
+	node    string // id of the device in Home Assistant
+	once    sync.Once
+	resync  = make(chan struct{}, 1) // asks to publish everything again, after a (re)connection
+	changed = make(chan struct{}, 1) // asks to publish the pin states now, after a command
+
+	mu       sync.Mutex        // guards retained
+	retained map[string]string // last payload published to each retained topic
+)
+
+// Status is the state of the bridge.
+type Status struct {
+	Enabled   bool   `json:"enabled"`
+	Connected bool   `json:"connected"`
+	Broker    string `json:"broker,omitempty"`
+	Topic     string `json:"topic,omitempty"` // base topic
+}
+
+// Initialize connects to MQTT_BROKER, unless it is empty, and starts
+// publishing. The connection is retried in the background while the broker
+// cannot be reached.
+func Initialize(ctx context.Context) error {
+	cfg := configs.Conf
+	if cfg.MQTTBroker == "" {
+		log.Println("MQTT: disabled")
+		return nil
+	}
+	once.Do(func() {
+		hostname, _ := os.Hostname()
+		clientID := cfg.MQTTClientID
+		if clientID == "" {
+			clientID = slug(cfg.AppName + "-" + hostname)
+		}
+		base = strings.TrimSuffix(cfg.MQTTTopic, "/")
+		if base == "" {
+			base = "raspc/" + slug(hostname)
+		}
+		node = slug(clientID)
+		retained = make(map[string]string)
+
+		opts := paho.NewClientOptions().
+			AddBroker(cfg.MQTTBroker).
+			SetClientID(clientID).
+			SetUsername(cfg.MQTTUsername).
+			SetPassword(cfg.MQTTPassword).
+			SetWill(base+"/"+statusTopic, "offline", qos, true).
+			SetAutoReconnect(true).
+			SetConnectRetry(true).
+			SetOnConnectHandler(onConnect).
+			SetConnectionLostHandler(func(_ paho.Client, err error) {
+				log.Println("MQTT: Connection lost:", err)
+			})
+		client = paho.NewClient(opts)
+		client.Connect()
+		log.Printf("MQTT: bridging %s to %s", base, cfg.MQTTBroker)
+
+		go run(ctx)
+	})
+	return nil
+}
+
+// GetStatus returns the state of the bridge.
+func GetStatus() Status {
+	if client == nil {
+		return Status{}
+	}
+	return Status{
+		Enabled:   true,
+		Connected: client.IsConnectionOpen(),
+		Broker:    configs.Conf.MQTTBroker,
+		Topic:     base,
+	}
+}
+
+// onConnect announces the device, subscribes to the commands and asks for
+// everything to be published again, as the broker may have lost it.
+func onConnect(c paho.Client) {
+	log.Println("MQTT: Connected")
+	c.Publish(base+"/"+statusTopic, qos, true, "online")
+	c.Subscribe(base+"/"+gpioTopic+"/+/+/set", qos, onCommand)
+	nudge(resync)
+}
+
+// nudge signals ch without blocking.
+func nudge(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func run(ctx context.Context) {
+	pins := time.NewTicker(pinsInterval)
+	defer pins.Stop()
+	// A zero interval disables the telemetry; a nil channel never receives.
+	var telemetry <-chan time.Time
+	if interval := configs.Conf.MQTTTelemetryInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		telemetry = ticker.C
+	}
+
+	for {
+		select {
+		case <-pins.C:
+			publishPins(false)
+		case <-changed:
+			publishPins(false)
+		case <-telemetry:
+			publishTelemetry(false)
+		case <-resync:
+			publishPins(true)
+			publishTelemetry(true)
+		case <-ctx.Done():
+			wait(client.Publish(base+"/"+statusTopic, qos, true, "offline"))
+			client.Disconnect(250)
+			return
+		}
+	}
+}
+
+// publish sends payload to topic as a retained message, unless it was the
+// last payload sent there and force is false.
+func publish(topic, payload string, force bool) {
+	if !client.IsConnectionOpen() {
+		return
+	}
+	mu.Lock()
+	last, ok := retained[topic]
+	mu.Unlock()
+	if ok && last == payload && !force {
+		return
+	}
+	if err := wait(client.Publish(topic, qos, true, payload)); err != nil {
+		log.Printf("MQTT: Error publishing to %s: %v", topic, err)
+		return
+	}
+	mu.Lock()
+	retained[topic] = payload
+	mu.Unlock()
+}
+
+// unpublish clears the retained message of topic.
+func unpublish(topic string) {
+	if !client.IsConnectionOpen() {
+		return
+	}
+	if err := wait(client.Publish(topic, qos, true, "")); err != nil {
+		log.Printf("MQTT: Error clearing %s: %v", topic, err)
+		return
+	}
+	mu.Lock()
+	delete(retained, topic)
+	mu.Unlock()
+}
+
+// wait waits for an MQTT operation to complete.
+func wait(token paho.Token) error {
+	if !token.WaitTimeout(10 * time.Second) {
+		return errors.New("timeout")
+	}
+	return token.Error()
+}
+
+var slugRegex = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// slug turns s into an id accepted in topics and by Home Assistant.
+func slug(s string) string {
+	return strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
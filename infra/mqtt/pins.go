@@ -0,0 +1,116 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+// ErrNotOutput is returned when a command targets a pin not configured as an output.
+var ErrNotOutput = errors.New("pin is not configured as an output")
+
+// pinConfigs are the discovery topics of the pins published last, so that
+// the ones of pins no longer labeled are cleared. Only used by run.
+var pinConfigs = make(map[string]bool)
+
+func pinTopic(line dto.Line) string {
+	return fmt.Sprintf("%s/%s/%s/%d", base, gpioTopic, line.Chip, line.Offset)
+}
+
+func onOff(value int) string {
+	if value == 1 {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// publishPins publishes the state of every held input and output pin and the
+// discovery payloads of the labeled ones.
+func publishPins(force bool) {
+	chips, err := gpio.GetAll()
+	if err != nil {
+		return
+	}
+
+	configs := make(map[string]bool)
+	for chip, pins := range chips {
+		for offset, state := range pins {
+			if !state.Held || state.Value == nil || state.Configured == nil {
+				continue
+			}
+			direction := state.Configured.Direction
+			if direction != dto.Input && direction != dto.Output {
+				continue
+			}
+			line := dto.Line{Chip: chip, Offset: offset}
+			publish(pinTopic(line)+"/state", onOff(*state.Value), force)
+
+			if discovery() && state.Label != nil {
+				topic, payload := pinDiscovery(line, direction, state.Label.Label)
+				publish(topic, payload, force)
+				configs[topic] = true
+			}
+		}
+	}
+
+	for topic := range pinConfigs {
+		if !configs[topic] {
+			unpublish(topic)
+		}
+	}
+	pinConfigs = configs
+}
+
+// onCommand sets an output pin from a message of gpio/<chip>/<pin>/set,
+// whose payload is ON, OFF, 1 or 0.
+func onCommand(_ paho.Client, msg paho.Message) {
+	parts := strings.Split(strings.TrimPrefix(msg.Topic(), base+"/"+gpioTopic+"/"), "/")
+	if len(parts) != 3 {
+		return
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil {
+		log.Printf("MQTT: Invalid pin in %s", msg.Topic())
+		return
+	}
+	var value int
+	switch strings.ToUpper(strings.TrimSpace(string(msg.Payload()))) {
+	case "ON", "1":
+		value = 1
+	case "OFF", "0":
+		value = 0
+	default:
+		log.Printf("MQTT: Invalid payload %q in %s, use ON, OFF, 1 or 0", msg.Payload(), msg.Topic())
+		return
+	}
+
+	line := dto.Line{Chip: parts[0], Offset: offset}
+	if err := setPin(line, value); err != nil {
+		log.Printf("MQTT: Error setting pin %s: %v", line, err)
+	}
+	nudge(changed)
+}
+
+// setPin drives an output pin to value, keeping the rest of its stored
+// configuration, through the same checks as the API.
+func setPin(line dto.Line, value int) error {
+	state, err := gpio.GetPin(line)
+	if err != nil {
+		return err
+	}
+	if state.Configured == nil || state.Configured.Direction != dto.Output {
+		return ErrNotOutput
+	}
+	mode := *state.Configured
+	mode.Value = value
+	if err := mode.Validation(); err != nil {
+		return err
+	}
+	return gpio.SetBool(mode, dto.Source{Type: dto.SourceMQTT})
+}
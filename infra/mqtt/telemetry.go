@@ -0,0 +1,24 @@
+package mqtt
+
+import (
+	"strconv"
+
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+// publishTelemetry publishes every metric that can be read and, on the first
+// successful reading, its discovery payload. Metrics failing, such as the
+// disks of missing mounts, are skipped.
+func publishTelemetry(force bool) {
+	for _, metric := range vchiq.Metrics {
+		value, err := vchiq.ReadMetric(metric)
+		if err != nil {
+			continue
+		}
+		if discovery() {
+			topic, payload := metricDiscovery(metric)
+			publish(topic, payload, force)
+		}
+		publish(base+"/"+telemetryTopic+"/"+metric, strconv.FormatFloat(value, 'f', -1, 64), force)
+	}
+}
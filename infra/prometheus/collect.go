@@ -0,0 +1,234 @@
+package prometheus
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+// collector writes the metric families of a source. A collector failing
+// writes nothing, so that a scrape never holds partial families.
+type collector struct {
+	name    string
+	collect func(e *exposition) error
+}
+
+var collectors = []collector{
+	{"cpu_temp", collectCPUTemp},
+	{"vcgencmd", collectVcgencmd},
+	{"throttled", collectThrottled},
+	{"mem_split", collectMemSplit},
+	{"memory", collectMemory},
+	{"disk", collectDisk},
+	{"net", collectNet},
+	{"load", collectLoad},
+	{"processes", collectProcesses},
+	{"gpio", collectGPIO},
+}
+
+// Scrape returns every metric in the text exposition format.
+func Scrape() []byte {
+	var e exposition
+	up := make([]bool, len(collectors))
+	for i, c := range collectors {
+		var part exposition
+		if err := c.collect(&part); err == nil {
+			e.buf.Write(part.buf.Bytes())
+			up[i] = true
+		}
+	}
+
+	e.family("raspc_collector_up", Gauge, "Whether the last collection of a source succeeded (1) or failed (0).")
+	for i, c := range collectors {
+		value := 0.0
+		if up[i] {
+			value = 1
+		}
+		e.sample("raspc_collector_up", value, "collector", c.name)
+	}
+
+	writeRequests(&e)
+	return e.buf.Bytes()
+}
+
+func collectCPUTemp(e *exposition) error {
+	temp, err := vchiq.ReadMetric(vchiq.MetricCPUTemp)
+	if err != nil {
+		return err
+	}
+	e.single("raspc_cpu_temperature_celsius", Gauge, "Temperature of the CPU thermal zone.", temp)
+	return nil
+}
+
+func collectVcgencmd(e *exposition) error {
+	gpuTemp, err := vchiq.ReadMetric(vchiq.MetricGPUTemp)
+	if err != nil {
+		return err
+	}
+	volt, err := vchiq.ReadMetric(vchiq.MetricCoreVolt)
+	if err != nil {
+		return err
+	}
+	freq, err := vchiq.ReadMetric(vchiq.MetricCPUFreq)
+	if err != nil {
+		return err
+	}
+	e.single("raspc_gpu_temperature_celsius", Gauge, "Temperature of the GPU reported by vcgencmd.", gpuTemp)
+	e.single("raspc_core_voltage_volts", Gauge, "Voltage of the core reported by vcgencmd.", volt)
+	e.single("raspc_cpu_frequency_hertz", Gauge, "Current clock of the ARM cores.", freq*1e6)
+	return nil
+}
+
+func collectThrottled(e *exposition) error {
+	throttled, err := vchiq.GetThrottled()
+	if err != nil {
+		return err
+	}
+	e.family("raspc_throttled", Gauge, "Bits of the get_throttled mask of the firmware, 1 when set.")
+	for _, flag := range vchiq.ThrottledFlags {
+		value := 0.0
+		if throttled&flag.Bit != 0 {
+			value = 1
+		}
+		e.sample("raspc_throttled", value, "flag", flag.Name)
+	}
+	return nil
+}
+
+func collectMemSplit(e *exposition) error {
+	arm, gpu, err := vchiq.GetMemSplit()
+	if err != nil {
+		return err
+	}
+	e.family("raspc_memory_split_bytes", Gauge, "Memory given to the ARM cores and to the GPU.")
+	e.sample("raspc_memory_split_bytes", arm, "memory", "arm")
+	e.sample("raspc_memory_split_bytes", gpu, "memory", "gpu")
+	return nil
+}
+
+func collectMemory(e *exposition) error {
+	total, free, _, err := vchiq.GetMemory()
+	if err != nil {
+		return err
+	}
+	e.single("raspc_memory_total_bytes", Gauge, "Total usable memory.", total)
+	e.single("raspc_memory_free_bytes", Gauge, "Free memory.", free)
+	return nil
+}
+
+// collectDisk reports the mounts of vchiq.DiskMounts that can be read; it
+// fails only when none can.
+func collectDisk(e *exposition) error {
+	type disk struct {
+		mount                  string
+		total, free, available float64
+	}
+	var disks []disk
+	var lastErr error
+	for _, mount := range vchiq.DiskMounts {
+		total, free, available, err := vchiq.GetDiskSize(mount)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		disks = append(disks, disk{mount, total, free, available})
+	}
+	if len(disks) == 0 {
+		return lastErr
+	}
+
+	e.family("raspc_disk_size_bytes", Gauge, "Size of the file system mounted at mount.")
+	for _, d := range disks {
+		e.sample("raspc_disk_size_bytes", d.total, "mount", d.mount)
+	}
+	e.family("raspc_disk_free_bytes", Gauge, "Free space of the file system mounted at mount.")
+	for _, d := range disks {
+		e.sample("raspc_disk_free_bytes", d.free, "mount", d.mount)
+	}
+	e.family("raspc_disk_available_bytes", Gauge, "Space of the file system mounted at mount available to unprivileged users.")
+	for _, d := range disks {
+		e.sample("raspc_disk_available_bytes", d.available, "mount", d.mount)
+	}
+	return nil
+}
+
+func collectNet(e *exposition) error {
+	nets, err := vchiq.GetNetStatistic()
+	if err != nil {
+		return err
+	}
+	sort.Slice(nets, func(i, j int) bool { return nets[i].Interface < nets[j].Interface })
+
+	e.family("raspc_network_receive_bytes_total", Counter, "Bytes received by a network interface.")
+	for _, n := range nets {
+		e.sample("raspc_network_receive_bytes_total", float64(n.RxBytes), "interface", n.Interface)
+	}
+	e.family("raspc_network_transmit_bytes_total", Counter, "Bytes sent by a network interface.")
+	for _, n := range nets {
+		e.sample("raspc_network_transmit_bytes_total", float64(n.TxBytes), "interface", n.Interface)
+	}
+	return nil
+}
+
+func collectLoad(e *exposition) error {
+	load1, load5, load15, err := vchiq.GetLoadAverages()
+	if err != nil {
+		return err
+	}
+	e.single("raspc_load1", Gauge, "1-minute load average.", load1)
+	e.single("raspc_load5", Gauge, "5-minute load average.", load5)
+	e.single("raspc_load15", Gauge, "15-minute load average.", load15)
+	return nil
+}
+
+func collectProcesses(e *exposition) error {
+	processes, err := vchiq.ListProcesses()
+	if err != nil {
+		return err
+	}
+	e.single("raspc_processes", Gauge, "Number of running processes.", float64(len(processes)))
+	return nil
+}
+
+// collectGPIO reports the level of the output pins held by the controller.
+func collectGPIO(e *exposition) error {
+	chips, err := gpio.GetAll()
+	if err != nil {
+		return err
+	}
+
+	type output struct {
+		line  dto.Line
+		label string
+		value int
+	}
+	var outputs []output
+	for chip, pins := range chips {
+		for offset, state := range pins {
+			if !state.Held || state.Value == nil || state.Configured == nil || state.Configured.Direction != dto.Output {
+				continue
+			}
+			o := output{line: dto.Line{Chip: chip, Offset: offset}, value: *state.Value}
+			if state.Label != nil {
+				o.label = state.Label.Label
+			}
+			outputs = append(outputs, o)
+		}
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].line.Chip != outputs[j].line.Chip {
+			return outputs[i].line.Chip < outputs[j].line.Chip
+		}
+		return outputs[i].line.Offset < outputs[j].line.Offset
+	})
+
+	e.family("raspc_gpio_output_value", Gauge, "Logical value of an output pin held by the controller.")
+	for _, o := range outputs {
+		e.sample("raspc_gpio_output_value", float64(o.value),
+			"chip", o.line.Chip, "pin", strconv.Itoa(o.line.Offset), "label", o.label)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package prometheus exports the system, GPIO and HTTP metrics of the
+// controller in the Prometheus text exposition format.
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types.
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// exposition builds a scrape in the text exposition format. The samples of a
+// metric family must be written right after its header.
+type exposition struct {
+	buf bytes.Buffer
+}
+
+// family writes the HELP and TYPE header of a metric family.
+func (e *exposition) family(name, typ, help string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	e.buf.WriteString("# HELP " + name + " " + help + "\n")
+	e.buf.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// sample writes a sample of name, labels being pairs of names and values.
+func (e *exposition) sample(name string, value float64, labels ...string) {
+	e.buf.WriteString(name)
+	if len(labels) > 0 {
+		e.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+		}
+		e.buf.WriteByte('}')
+	}
+	e.buf.WriteByte(' ')
+	e.buf.WriteString(formatValue(value))
+	e.buf.WriteByte('\n')
+}
+
+// single writes a family holding a single sample.
+func (e *exposition) single(name, typ, help string, value float64) {
+	e.family(name, typ, help)
+	e.sample(name, value)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
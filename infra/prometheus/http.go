@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// requestKey identifies a series of the HTTP request counter.
+type requestKey struct {
+	method string
+	route  string // route pattern, e.g. /api/gpio/:pin, to bound the number of series
+	status int
+}
+
+var (
+	requestsMu sync.Mutex
+	requests   = make(map[requestKey]uint64)
+)
+
+// CountRequests godoc
+// @description Middleware counting the handled requests per method, route and status.
+func CountRequests(c *fiber.Ctx) error {
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		status = fiberErr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+	key := requestKey{method: utils.CopyString(c.Method()), route: c.Route().Path, status: status}
+
+	requestsMu.Lock()
+	requests[key]++
+	requestsMu.Unlock()
+	return err
+}
+
+// writeRequests writes the HTTP request counter.
+func writeRequests(e *exposition) {
+	requestsMu.Lock()
+	keys := make([]requestKey, 0, len(requests))
+	for key := range requests {
+		keys = append(keys, key)
+	}
+	counts := make(map[requestKey]uint64, len(requests))
+	for key, count := range requests {
+		counts[key] = count
+	}
+	requestsMu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	e.family("raspc_http_requests_total", Counter, "HTTP requests handled, by method, route and status code.")
+	for _, key := range keys {
+		e.sample("raspc_http_requests_total", float64(counts[key]),
+			"method", key.method, "route", key.route, "code", strconv.Itoa(key.status))
+	}
+}
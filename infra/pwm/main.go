@@ -0,0 +1,169 @@
+package pwm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+)
+
+var (
+	sysfs       *Sysfs
+	mu          sync.RWMutex
+	once        sync.Once
+	lastRestore = RestoreReport{Failed: make(map[string]string)}
+)
+
+// RestoreReport describes the outcome of replaying the stored channels on startup.
+type RestoreReport struct {
+	Restored []string          `json:"restored"`
+	Skipped  []string          `json:"skipped"`
+	Failed   map[string]string `json:"failed"`
+}
+
+// Initialize opens the PWM sysfs root and replays the channels stored in the database.
+func Initialize(ctx context.Context) error {
+	var initErr error
+	once.Do(func() {
+		if _, err := os.Stat(configs.Conf.PWMRoot); err != nil {
+			initErr = fmt.Errorf("Error opening PWM sysfs root: %w", err)
+			return
+		}
+		if ctx.Err() != nil {
+			initErr = ctx.Err()
+			return
+		}
+
+		mu.Lock()
+		sysfs = &Sysfs{Root: configs.Conf.PWMRoot}
+		mu.Unlock()
+		log.Println("PWM sysfs initialized")
+
+		report := restoreChannels()
+		log.Printf("PWM: restored %d channels, skipped %d, failed %d",
+			len(report.Restored), len(report.Skipped), len(report.Failed))
+	})
+	return initErr
+}
+
+// CheckSysfs reports whether the PWM subsystem is initialized.
+func CheckSysfs() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return sysfs != nil
+}
+
+// GetAll returns every PWM chip with the state of its channels.
+func GetAll() ([]ChipInfo, error) {
+	if !CheckSysfs() {
+		return nil, errors.New("PWM not initialized")
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return sysfs.Chips()
+}
+
+// GetChannel returns the state of a PWM channel.
+func GetChannel(chip, channel int) (ChannelState, error) {
+	if !CheckSysfs() {
+		return ChannelState{}, errors.New("PWM not initialized")
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return sysfs.Channel(chip, channel)
+}
+
+// Set exports and configures a PWM channel and stores its configuration.
+func Set(ch dto.PWMChannel) error {
+	if !CheckSysfs() {
+		return errors.New("PWM not initialized")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := apply(ch); err != nil {
+		return err
+	}
+
+	if err := db.SetPWM(ch); err != nil {
+		return fmt.Errorf("PWM: Error setting channel in database: %w", err)
+	}
+
+	log.Printf("PWM: chip %d channel %d set to period %dns, duty cycle %dns, enabled %t",
+		ch.Chip, ch.Channel, ch.Period, ch.DutyCycle, ch.Enable)
+	return nil
+}
+
+// apply writes ch to sysfs.
+// The caller must hold mu.
+func apply(ch dto.PWMChannel) error {
+	if err := sysfs.Export(ch.Chip, ch.Channel); err != nil {
+		return err
+	}
+	return sysfs.Apply(ch.Chip, ch.Channel, ch.Period, ch.DutyCycle, ch.Polarity, ch.Enable)
+}
+
+// restoreChannels applies every channel stored in the database again, following its restore policy.
+func restoreChannels() RestoreReport {
+	report := RestoreReport{Failed: make(map[string]string)}
+
+	channels, err := db.GetPWMMap()
+	if err != nil {
+		log.Println("PWM: no stored channels to restore:", err)
+		setRestoreReport(report)
+		return report
+	}
+
+	keys := make([]string, 0, len(channels))
+	for key := range channels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mu.Lock()
+	for _, key := range keys {
+		ch := channels[key]
+
+		switch ch.Restore {
+		case dto.RestoreNone:
+			report.Skipped = append(report.Skipped, key)
+			continue
+		case dto.RestoreSafe:
+			ch.Enable = false
+		}
+
+		if err := apply(ch); err != nil {
+			log.Println(err)
+			report.Failed[key] = err.Error()
+			continue
+		}
+		report.Restored = append(report.Restored, key)
+	}
+	mu.Unlock()
+
+	setRestoreReport(report)
+	return report
+}
+
+func setRestoreReport(report RestoreReport) {
+	mu.Lock()
+	defer mu.Unlock()
+	lastRestore = report
+}
+
+// GetRestoreReport returns the report of the last startup restore.
+func GetRestoreReport() RestoreReport {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lastRestore
+}
@@ -0,0 +1,242 @@
+package pwm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportTimeout is how long to wait for udev to create an exported channel.
+const exportTimeout = time.Second
+
+var ErrChannelNotFound = errors.New("PWM channel not found")
+
+// writeFile writes the sysfs attributes, it is replaced in tests to record the writes.
+var writeFile = os.WriteFile
+
+// Sysfs accesses the PWM chips exposed under a sysfs class directory,
+// normally /sys/class/pwm.
+type Sysfs struct {
+	Root string
+}
+
+// ChipInfo describes a PWM chip and the state of its channels.
+type ChipInfo struct {
+	Chip     int            `json:"chip"`
+	Name     string         `json:"name"`
+	NPWM     int            `json:"npwm"`
+	Channels []ChannelState `json:"channels"`
+}
+
+// ChannelState is the state of a PWM channel as read from sysfs.
+type ChannelState struct {
+	Chip      int    `json:"chip"`
+	Channel   int    `json:"channel"`
+	Exported  bool   `json:"exported"`
+	Period    int64  `json:"period"`
+	DutyCycle int64  `json:"duty_cycle"`
+	Polarity  string `json:"polarity"`
+	Enabled   bool   `json:"enabled"`
+}
+
+func (s Sysfs) chipDir(chip int) string {
+	return filepath.Join(s.Root, fmt.Sprintf("pwmchip%d", chip))
+}
+
+func (s Sysfs) channelDir(chip, channel int) string {
+	return filepath.Join(s.chipDir(chip), fmt.Sprintf("pwm%d", channel))
+}
+
+// Chips lists the PWM chips found under Root.
+func (s Sysfs) Chips() ([]ChipInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(s.Root, "pwmchip*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var chips []ChipInfo
+	for _, dir := range matches {
+		name := filepath.Base(dir)
+		chip, err := strconv.Atoi(strings.TrimPrefix(name, "pwmchip"))
+		if err != nil {
+			continue
+		}
+		npwm, err := s.NPWM(chip)
+		if err != nil {
+			return nil, err
+		}
+
+		info := ChipInfo{Chip: chip, Name: name, NPWM: npwm}
+		for channel := 0; channel < npwm; channel++ {
+			state, err := s.Channel(chip, channel)
+			if err != nil {
+				return nil, err
+			}
+			info.Channels = append(info.Channels, state)
+		}
+		chips = append(chips, info)
+	}
+
+	sort.Slice(chips, func(i, j int) bool { return chips[i].Chip < chips[j].Chip })
+	return chips, nil
+}
+
+// NPWM returns the number of channels of a chip.
+func (s Sysfs) NPWM(chip int) (int, error) {
+	value, err := readString(filepath.Join(s.chipDir(chip), "npwm"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrChannelNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// Channel reads the state of a channel, which is reported as not exported if
+// its directory does not exist.
+func (s Sysfs) Channel(chip, channel int) (ChannelState, error) {
+	if err := s.checkChannel(chip, channel); err != nil {
+		return ChannelState{}, err
+	}
+
+	state := ChannelState{Chip: chip, Channel: channel}
+	dir := s.channelDir(chip, channel)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	state.Exported = true
+
+	var err error
+	if state.Period, err = readInt(filepath.Join(dir, "period")); err != nil {
+		return state, err
+	}
+	if state.DutyCycle, err = readInt(filepath.Join(dir, "duty_cycle")); err != nil {
+		return state, err
+	}
+	if state.Polarity, err = readString(filepath.Join(dir, "polarity")); err != nil {
+		return state, err
+	}
+	enable, err := readString(filepath.Join(dir, "enable"))
+	if err != nil {
+		return state, err
+	}
+	state.Enabled = enable == "1"
+	return state, nil
+}
+
+// Export exports a channel if needed and waits for its directory to appear.
+func (s Sysfs) Export(chip, channel int) error {
+	if err := s.checkChannel(chip, channel); err != nil {
+		return err
+	}
+
+	dir := s.channelDir(chip, channel)
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := writeString(filepath.Join(s.chipDir(chip), "export"), strconv.Itoa(channel)); err != nil {
+		return fmt.Errorf("PWM: Error exporting channel %d of chip %d: %w", channel, chip, err)
+	}
+
+	deadline := time.Now().Add(exportTimeout)
+	for {
+		// The attributes are writable only once udev has fixed their permissions.
+		if f, err := os.OpenFile(filepath.Join(dir, "enable"), os.O_WRONLY, 0); err == nil {
+			_ = f.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("PWM: channel %d of chip %d did not appear after export", channel, chip)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Apply writes the period, duty cycle, polarity and enable state of an exported channel.
+func (s Sysfs) Apply(chip, channel int, period, dutyCycle int64, polarity string, enable bool) error {
+	dir := s.channelDir(chip, channel)
+	current, err := s.Channel(chip, channel)
+	if err != nil {
+		return err
+	}
+
+	// The polarity can only be changed while the channel is disabled.
+	if current.Enabled && (!enable || (polarity != "" && polarity != current.Polarity)) {
+		if err := writeString(filepath.Join(dir, "enable"), "0"); err != nil {
+			return err
+		}
+	}
+
+	// The duty cycle may never exceed the period, so a growing period is written
+	// before the duty cycle and a shrinking one after it.
+	if period > current.Period {
+		err = writeInt(filepath.Join(dir, "period"), period)
+		if err == nil {
+			err = writeInt(filepath.Join(dir, "duty_cycle"), dutyCycle)
+		}
+	} else {
+		err = writeInt(filepath.Join(dir, "duty_cycle"), dutyCycle)
+		if err == nil {
+			err = writeInt(filepath.Join(dir, "period"), period)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("PWM: Error setting channel %d of chip %d: %w", channel, chip, err)
+	}
+
+	if polarity != "" && polarity != current.Polarity {
+		if err := writeString(filepath.Join(dir, "polarity"), polarity); err != nil {
+			return fmt.Errorf("PWM: Error setting polarity of channel %d of chip %d: %w", channel, chip, err)
+		}
+	}
+
+	if enable {
+		return writeString(filepath.Join(dir, "enable"), "1")
+	}
+	return nil
+}
+
+// Disable stops the output of an exported channel.
+func (s Sysfs) Disable(chip, channel int) error {
+	return writeString(filepath.Join(s.channelDir(chip, channel), "enable"), "0")
+}
+
+func (s Sysfs) checkChannel(chip, channel int) error {
+	npwm, err := s.NPWM(chip)
+	if err != nil {
+		return err
+	}
+	if channel < 0 || channel >= npwm {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+func readString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readInt(path string) (int64, error) {
+	value, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func writeString(path, value string) error {
+	return writeFile(path, []byte(value), 0644)
+}
+
+func writeInt(path string, value int64) error {
+	return writeString(path, strconv.FormatInt(value, 10))
+}
@@ -0,0 +1,165 @@
+package pwm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSysfs creates a sysfs tree with a chip of two channels, the first one
+// exported with the given attributes, and records the attribute writes. Writing
+// the export file creates the channel directory, as the kernel does.
+func fakeSysfs(t *testing.T, period, dutyCycle, enable string) (Sysfs, *[]string) {
+	t.Helper()
+	root := t.TempDir()
+	s := Sysfs{Root: root}
+	mustWrite(t, filepath.Join(s.chipDir(0), "npwm"), "2\n")
+	mustWrite(t, filepath.Join(s.chipDir(0), "export"), "")
+	createChannel(t, s, 0, 0, period, dutyCycle, enable)
+
+	var writes []string
+	writeFile = func(path string, data []byte, perm os.FileMode) error {
+		rel, _ := filepath.Rel(root, path)
+		writes = append(writes, rel+"="+string(data))
+		if filepath.Base(path) == "export" {
+			createChannel(t, s, 0, 1, "0", "0", "0")
+		}
+		return os.WriteFile(path, data, perm)
+	}
+	t.Cleanup(func() { writeFile = os.WriteFile })
+	return s, &writes
+}
+
+func createChannel(t *testing.T, s Sysfs, chip, channel int, period, dutyCycle, enable string) {
+	t.Helper()
+	dir := s.channelDir(chip, channel)
+	mustWrite(t, filepath.Join(dir, "period"), period+"\n")
+	mustWrite(t, filepath.Join(dir, "duty_cycle"), dutyCycle+"\n")
+	mustWrite(t, filepath.Join(dir, "polarity"), "normal\n")
+	mustWrite(t, filepath.Join(dir, "enable"), enable+"\n")
+}
+
+func mustWrite(t *testing.T, path, value string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChips(t *testing.T) {
+	s, _ := fakeSysfs(t, "1000000", "250000", "1")
+	mustWrite(t, filepath.Join(s.Root, "pwmchip2", "npwm"), "1\n")
+
+	chips, err := s.Chips()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chips) != 2 || chips[0].Chip != 0 || chips[1].Chip != 2 {
+		t.Fatalf("chips = %+v, want pwmchip0 and pwmchip2", chips)
+	}
+	if chips[0].Name != "pwmchip0" || chips[0].NPWM != 2 || len(chips[0].Channels) != 2 {
+		t.Fatalf("chip 0 = %+v", chips[0])
+	}
+	want := ChannelState{Chip: 0, Channel: 0, Exported: true, Period: 1000000, DutyCycle: 250000, Polarity: "normal", Enabled: true}
+	if got := chips[0].Channels[0]; got != want {
+		t.Errorf("channel 0 = %+v, want %+v", got, want)
+	}
+	if got := chips[0].Channels[1]; got.Exported {
+		t.Errorf("channel 1 = %+v, want not exported", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	s, writes := fakeSysfs(t, "0", "0", "0")
+
+	if err := s.Export(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(*writes) != 0 {
+		t.Errorf("exporting an exported channel wrote %v", *writes)
+	}
+
+	if err := s.Export(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(*writes, " "); got != "pwmchip0/export=1" {
+		t.Errorf("writes = %s, want pwmchip0/export=1", got)
+	}
+	if state, err := s.Channel(0, 1); err != nil || !state.Exported {
+		t.Errorf("channel 1 = %+v, %v, want exported", state, err)
+	}
+
+	if err := s.Export(0, 2); err != ErrChannelNotFound {
+		t.Errorf("Export(0, 2) = %v, want %v", err, ErrChannelNotFound)
+	}
+	if err := s.Export(1, 0); err != ErrChannelNotFound {
+		t.Errorf("Export(1, 0) = %v, want %v", err, ErrChannelNotFound)
+	}
+}
+
+func TestApplyWriteOrder(t *testing.T) {
+	tests := []struct {
+		name               string
+		period, duty       string // current attributes
+		enable             string
+		newPeriod, newDuty int64
+		want               []string
+	}{
+		{
+			name:   "period grows",
+			period: "1000", duty: "500", enable: "0",
+			newPeriod: 4000, newDuty: 3000,
+			want: []string{"period=4000", "duty_cycle=3000", "enable=1"},
+		},
+		{
+			name:   "period grows, duty cycle within the old period",
+			period: "1000", duty: "500", enable: "0",
+			newPeriod: 4000, newDuty: 800,
+			want: []string{"period=4000", "duty_cycle=800", "enable=1"},
+		},
+		{
+			name:   "period shrinks",
+			period: "4000", duty: "3000", enable: "1",
+			newPeriod: 1000, newDuty: 500,
+			want: []string{"duty_cycle=500", "period=1000", "enable=1"},
+		},
+		{
+			name:   "same period",
+			period: "1000", duty: "200", enable: "1",
+			newPeriod: 1000, newDuty: 700,
+			want: []string{"duty_cycle=700", "period=1000", "enable=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, writes := fakeSysfs(t, tt.period, tt.duty, tt.enable)
+			if err := s.Apply(0, 0, tt.newPeriod, tt.newDuty, "", true); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, w := range *writes {
+				got = append(got, strings.TrimPrefix(w, "pwmchip0/pwm0/"))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("writes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPolarity(t *testing.T) {
+	s, writes := fakeSysfs(t, "1000", "500", "1")
+	if err := s.Apply(0, 0, 1000, 500, "inversed", true); err != nil {
+		t.Fatal(err)
+	}
+	want := "pwmchip0/pwm0/enable=0 pwmchip0/pwm0/duty_cycle=500 pwmchip0/pwm0/period=1000 " +
+		"pwmchip0/pwm0/polarity=inversed pwmchip0/pwm0/enable=1"
+	if got := strings.Join(*writes, " "); got != want {
+		t.Errorf("writes = %s, want %s", got, want)
+	}
+}
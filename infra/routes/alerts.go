@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/alerts"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getAlerts godoc
+// @description Returns every alert with its state.
+// @tags alerts
+// @url /api/alerts
+func getAlerts(c *fiber.Ctx) error {
+	list, err := alerts.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"alerts": list,
+	})
+}
+
+// getAlert godoc
+// @description Returns an alert with its state.
+// @tags alerts
+// @url /api/alerts/{name}
+func getAlert(c *fiber.Ctx) error {
+	alert, err := alerts.Get(c.Params("name"))
+	if errors.Is(err, db.ErrAlertNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(alert)
+}
+
+// updateAlert godoc
+// @description Creates or replaces an alert.
+// @tags alerts
+// @url /api/alerts/{name}
+func updateAlert(c *fiber.Ctx) error {
+	var alert dto.AlertRule
+	if err := c.BodyParser(&alert); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	alert.Name = utils.CopyString(c.Params("name"))
+	if err := alert.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	status, err := alerts.Set(alert)
+	if errors.Is(err, alerts.ErrInvalidAlert) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(status)
+}
+
+// deleteAlert godoc
+// @description Removes an alert and its state.
+// @tags alerts
+// @url /api/alerts/{name}
+func deleteAlert(c *fiber.Ctx) error {
+	err := alerts.Delete(c.Params("name"))
+	if errors.Is(err, db.ErrAlertNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Alert removed",
+	})
+}
+
+// getNotifiers godoc
+// @description Returns the notifiers of the configuration.
+// @tags alerts
+// @url /api/alerts/notifiers
+func getNotifiers(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"notifiers": alerts.Notifiers(),
+	})
+}
+
+// testNotifier godoc
+// @description Sends a test notification through a notifier.
+// @tags alerts
+// @url /api/alerts/notifiers/{name}/test
+func testNotifier(c *fiber.Ctx) error {
+	err := alerts.TestNotifier(c.Params("name"))
+	if errors.Is(err, alerts.ErrUnknownNotifier) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Notification sent",
+	})
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getAudit godoc
+// @description Returns the changes of every pin, newest first.
+// @tags gpio
+// @url /api/audit?from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z&limit=100&before={id}
+func getAudit(c *fiber.Ctx) error {
+	return history(c, nil)
+}
+
+// getGpioHistory godoc
+// @description Returns the changes of a pin, newest first.
+// @tags gpio
+// @url /api/gpio/{pin}/history?from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z&limit=100&before={id}
+func getGpioHistory(c *fiber.Ctx) error {
+	line, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return history(c, &line)
+}
+
+// history responds with a page of the audit log, of line only if not nil.
+func history(c *fiber.Ctx, line *dto.Line) error {
+	q := dto.AuditQuery{
+		Line:   line,
+		Before: utils.CopyString(c.Query("before")),
+		Limit:  c.QueryInt("limit", 100),
+	}
+	if q.Limit <= 0 || q.Limit > dto.MaxAuditLimit {
+		q.Limit = dto.MaxAuditLimit
+	}
+	for param, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid " + param + ", use an RFC 3339 time such as 2024-05-01T00:00:00Z",
+				})
+			}
+			*t = parsed
+		}
+	}
+
+	changes, next, err := gpio.GetHistory(q)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"changes": changes,
+		"next":    next,
+	})
+}
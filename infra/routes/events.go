@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// keepAliveInterval is how often an idle event stream is probed for disconnected clients.
+const keepAliveInterval = 15 * time.Second
+
+// getGpioEvents godoc
+// @description Streams the edge events of an input pin, as Server-Sent Events or over a WebSocket upgrade.
+// @tags gpio
+// @url /api/gpio/{pin}/events?edge=both&debounce=10ms
+func getGpioEvents(c *fiber.Ctx) error {
+	if !gpio.CheckChip() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "GPIO chip not initialized",
+		})
+	}
+
+	pin, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var sub dto.EventSubscription
+	if err := c.QueryParser(&sub); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := sub.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if websocket.IsWebSocketUpgrade(c) {
+		c.Locals("pin", pin)
+		c.Locals("subscription", sub)
+		return c.Next()
+	}
+
+	events, unsubscribe, err := gpio.Subscribe(pin, sub)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	// The connection is not reused after the stream, so that it can be read
+	// below to notice the client leaving.
+	c.Context().SetConnectionClose()
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		// The client is not expected to send anything, reading only detects the close.
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			buf := make([]byte, 1)
+			for {
+				if _, err := conn.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				data, _ := json.Marshal(ev)
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Edge, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case <-gone:
+				return
+			}
+			// A failed flush means the client went away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+	return nil
+}
+
+// wsGpioEvents streams the edge events of an input pin over a WebSocket.
+func wsGpioEvents(conn *websocket.Conn) {
+	pin := conn.Locals("pin").(dto.Line)
+	sub := conn.Locals("subscription").(dto.EventSubscription)
+
+	events, unsubscribe, err := gpio.Subscribe(pin, sub)
+	if err != nil {
+		_ = conn.WriteJSON(fiber.Map{"error": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	// The client is not expected to send anything, reading only detects the close.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package routes
 
 import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/db"
 	"github.com/gabrielmoura/raspController/infra/gpio"
 	"github.com/gabrielmoura/raspController/internal/dto"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
 // getGpio godoc
-// @description Returns the status of all configured GPIO pins.
+// @description Returns the configured and measured status of all configured GPIO pins.
 // @tags gpio
 // @url /api/gpio
 func getGpio(c *fiber.Ctx) error {
@@ -28,6 +32,38 @@ func getGpio(c *fiber.Ctx) error {
 	})
 }
 
+// getGpioPin godoc
+// @description Returns the configured and measured status of a GPIO pin.
+// @tags gpio
+// @url /api/gpio/{pin}
+func getGpioPin(c *fiber.Ctx) error {
+	if !gpio.CheckChip() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "GPIO chip not initialized",
+		})
+	}
+
+	pin, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	state, err := gpio.GetPin(pin)
+	if errors.Is(err, db.ErrPinNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(state)
+}
+
 // getGpioAll All GPIO pins from the GPIO chip.
 func getGpioAll(c *fiber.Ctx) error {
 	if !gpio.CheckChip() {
@@ -47,6 +83,20 @@ func getGpioAll(c *fiber.Ctx) error {
 	})
 }
 
+// getGpioRestore godoc
+// @description Returns the result of restoring the stored pins on startup.
+// @tags gpio
+// @url /api/gpio/restore
+func getGpioRestore(c *fiber.Ctx) error {
+	if !gpio.CheckChip() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "GPIO chip not initialized",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(gpio.GetRestoreReport())
+}
+
 // updateGpio godoc
 // @description Updates the status of a GPIO pin.
 // @tags gpio
@@ -68,12 +118,13 @@ func updateGpio(c *fiber.Ctx) error {
 		})
 	}
 
-	pinMode.Pin, err = c.ParamsInt("pin")
+	line, err := pinParam(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	pinMode.Chip, pinMode.Pin = line.Chip, line.Offset
 	if err := pinMode.Validation(); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -81,7 +132,12 @@ func updateGpio(c *fiber.Ctx) error {
 	}
 
 	// Set the pin value on the GPIO chip.
-	if err = gpio.SetBool(pinMode); err != nil {
+	err = gpio.SetBool(pinMode, source(c))
+	if errors.Is(err, gpio.ErrInterlock) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -89,3 +145,349 @@ func updateGpio(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(pinMode)
 }
+
+// updateGpioBatch godoc
+// @description Sets several output pins of the same chip at once, in a single line request.
+// @tags gpio
+// @url /api/gpio
+func updateGpioBatch(c *fiber.Ctx) error {
+	if !gpio.CheckChip() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "GPIO chip not initialized",
+		})
+	}
+
+	var pins []dto.PinMode
+	if err := c.BodyParser(&pins); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	for i := range pins {
+		if err := pins[i].Validation(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	err := gpio.SetBatch(pins, source(c))
+	if errors.Is(err, gpio.ErrInvalidBatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if errors.Is(err, gpio.ErrInterlock) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"pins": pins,
+	})
+}
+
+// pulseGpio godoc
+// @description Pulses an output pin away from its resting level, optionally repeating as a blink pattern.
+// @tags gpio
+// @url /api/gpio/{pin}/pulse
+func pulseGpio(c *fiber.Ctx) error {
+	if !gpio.CheckChip() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "GPIO chip not initialized",
+		})
+	}
+
+	pin, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var pulse dto.Pulse
+	if err := c.BodyParser(&pulse); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := pulse.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := gpio.Pulse(pin, pulse, source(c)); err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(pulse)
+}
+
+// cancelPulseGpio godoc
+// @description Cancels the pulse running on a pin, returning it to its resting level.
+// @tags gpio
+// @url /api/gpio/{pin}/pulse
+func cancelPulseGpio(c *fiber.Ctx) error {
+	pin, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := gpio.CancelPulse(pin); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Pulse cancelled",
+	})
+}
+
+// simulateGpio godoc
+// @description Drives a pin of a simulated chip to a level, as an external device would.
+// @tags gpio
+// @url /api/gpio/{pin}/simulate
+func simulateGpio(c *fiber.Ctx) error {
+	line, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var input dto.SimulatedInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = gpio.SimulateInput(line, input.Value)
+	if errors.Is(err, gpio.ErrUnknownPin) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(input)
+}
+
+// getLeases godoc
+// @description Returns the watchdogs of every pin.
+// @tags gpio
+// @url /api/gpio/leases
+func getLeases(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"leases": gpio.GetLeases(),
+	})
+}
+
+// armLease godoc
+// @description Arms the watchdog of an output pin: unless a heartbeat arrives within the timeout the pin is driven to its safe value.
+// @tags gpio
+// @url /api/gpio/{pin}/lease
+func armLease(c *fiber.Ctx) error {
+	if !gpio.CheckChip() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "GPIO chip not initialized",
+		})
+	}
+
+	var lease dto.Lease
+	err := c.BodyParser(&lease)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	line, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	lease.Chip, lease.Pin = line.Chip, line.Offset
+	if err := lease.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	state, err := gpio.Arm(lease)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(state)
+}
+
+// heartbeatLease godoc
+// @description Renews the watchdog of a pin for another timeout.
+// @tags gpio
+// @url /api/gpio/{pin}/heartbeat
+func heartbeatLease(c *fiber.Ctx) error {
+	line, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	state, err := gpio.Heartbeat(line)
+	if errors.Is(err, gpio.ErrNoLease) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if errors.Is(err, gpio.ErrLeaseTripped) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+			"lease": state,
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(state)
+}
+
+// disarmLease godoc
+// @description Removes the watchdog of a pin, leaving the pin as it is.
+// @tags gpio
+// @url /api/gpio/{pin}/lease
+func disarmLease(c *fiber.Ctx) error {
+	line, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = gpio.Disarm(line)
+	if errors.Is(err, gpio.ErrNoLease) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Lease removed",
+	})
+}
+
+// pinParam resolves the :pin parameter, given as a line offset on the default chip,
+// as chip:offset or as a pin label. The parameter is copied as the line outlives the request.
+func pinParam(c *fiber.Ctx) (dto.Line, error) {
+	return gpio.ResolvePin(utils.CopyString(c.Params("pin")))
+}
+
+// source identifies the client of the request in the audit log.
+func source(c *fiber.Ctx) dto.Source {
+	token, _ := c.Locals("token").(string)
+	return dto.Source{Type: dto.SourceAPI, IP: c.IP(), Token: token}
+}
+
+// getPins godoc
+// @description Returns the GPIO header of the detected board and the pin labels.
+// @tags gpio
+// @url /api/pins
+func getPins(c *fiber.Ctx) error {
+	board, err := gpio.GetBoardMap()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(board)
+}
+
+// updatePinLabel godoc
+// @description Sets the label, description and tags of a pin.
+// @tags gpio
+// @url /api/pins/{pin}
+func updatePinLabel(c *fiber.Ctx) error {
+	var label dto.PinLabel
+	err := c.BodyParser(&label)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	line, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	label.Chip, label.Pin = line.Chip, line.Offset
+	if err := label.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = gpio.SetLabel(label)
+	if errors.Is(err, db.ErrLabelExists) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(label)
+}
+
+// deletePinLabel godoc
+// @description Removes the label of a pin.
+// @tags gpio
+// @url /api/pins/{pin}
+func deletePinLabel(c *fiber.Ctx) error {
+	pin, err := pinParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = gpio.DeleteLabel(pin)
+	if errors.Is(err, db.ErrLabelNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Label deleted",
+	})
+}
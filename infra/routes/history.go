@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getHistoryMetrics godoc
+// @description Returns the metrics of the history and the resolutions they are kept at.
+// @tags info
+// @url /api/history
+func getHistoryMetrics(c *fiber.Ctx) error {
+	tiers := make([]fiber.Map, 0, len(db.HistoryTiers))
+	for _, tier := range db.HistoryTiers {
+		tiers = append(tiers, fiber.Map{
+			"name":      tier.Name,
+			"step":      tier.Step.String(),
+			"retention": tier.Retention.String(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"metrics": vchiq.Metrics,
+		"tiers":   tiers,
+	})
+}
+
+// getHistory godoc
+// @description Returns the past values of a metric, oldest first, aggregated per step.
+// @tags info
+// @url /api/history/{metric}?from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z&step=5m
+func getHistory(c *fiber.Ctx) error {
+	q := dto.MetricQuery{
+		Metric: utils.CopyString(c.Params("metric")),
+		To:     time.Now(),
+	}
+	known := false
+	for _, metric := range vchiq.Metrics {
+		known = known || metric == q.Metric
+	}
+	if !known {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": vchiq.ErrUnknownMetric.Error(),
+		})
+	}
+
+	q.From = q.To.Add(-time.Hour)
+	for param, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid " + param + ", use an RFC 3339 time such as 2024-05-01T00:00:00Z",
+				})
+			}
+			*t = parsed
+		}
+	}
+	if value := c.Query("step"); value != "" {
+		step, err := time.ParseDuration(value)
+		if err != nil || step < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid step, use a duration such as 5m",
+			})
+		}
+		q.Step = step
+	}
+
+	points, tier, err := db.GetSamples(q)
+	if errors.Is(err, db.ErrInvalidRange) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	step := q.Step
+	if step < tier.Step {
+		step = tier.Step
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"metric": q.Metric,
+		"from":   q.From,
+		"to":     q.To,
+		"step":   step.String(),
+		"tier":   tier.Name,
+		"points": points,
+	})
+}
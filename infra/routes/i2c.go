@@ -0,0 +1,212 @@
+package routes
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/i2c"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getI2C godoc
+// @description Returns the I2C buses and the device drivers.
+// @tags i2c
+// @url /api/i2c
+func getI2C(c *fiber.Ctx) error {
+	buses, err := i2c.Buses()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"buses":   buses,
+		"drivers": i2c.Drivers(),
+	})
+}
+
+// scanI2C godoc
+// @description Returns the addresses that answer on an I2C bus.
+// @tags i2c
+// @url /api/i2c/{bus}
+func scanI2C(c *fiber.Ctx) error {
+	bus, err := c.ParamsInt("bus")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	found, err := i2c.Scan(bus)
+	if err != nil {
+		return i2cError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"bus":       bus,
+		"addresses": found,
+	})
+}
+
+// readI2C godoc
+// @description Reads bytes from a device, from a register if one is given.
+// @tags i2c
+// @url /api/i2c/{bus}/{addr}
+func readI2C(c *fiber.Ctx) error {
+	t := dto.I2CTransfer{Read: c.QueryInt("read", 1)}
+	if reg := c.Query("register"); reg != "" {
+		n, err := strconv.ParseUint(reg, 0, 8)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid register, use 0 to 255 or 0x00 to 0xff",
+			})
+		}
+		register := int(n)
+		t.Register = &register
+	}
+	return transferI2C(c, t)
+}
+
+// writeI2C godoc
+// @description Runs a raw transaction with a device: writes a register and bytes, then reads bytes.
+// @tags i2c
+// @url /api/i2c/{bus}/{addr}
+func writeI2C(c *fiber.Ctx) error {
+	var t dto.I2CTransfer
+	if err := c.BodyParser(&t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return transferI2C(c, t)
+}
+
+// transferI2C runs t with the device of the bus and addr route parameters.
+func transferI2C(c *fiber.Ctx, t dto.I2CTransfer) error {
+	bus, err := c.ParamsInt("bus")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	addr, err := dto.ParseI2CAddr(c.Params("addr"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := t.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	read, err := i2c.Transfer(bus, addr, t)
+	if err != nil {
+		return i2cError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"bus":     bus,
+		"address": addr,
+		"read":    read,
+	})
+}
+
+// i2cError answers a failed bus access: 404 for a bus or device that is not there.
+func i2cError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, i2c.ErrUnknownBus) || errors.Is(err, i2c.ErrNoAck) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// getI2CDevices godoc
+// @description Returns every I2C device with its readings.
+// @tags i2c
+// @url /api/i2c/devices
+func getI2CDevices(c *fiber.Ctx) error {
+	list, err := i2c.ListDevices()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"devices": list,
+	})
+}
+
+// getI2CDevice godoc
+// @description Returns an I2C device with its readings.
+// @tags i2c
+// @url /api/i2c/devices/{name}
+func getI2CDevice(c *fiber.Ctx) error {
+	device, err := i2c.GetDevice(c.Params("name"))
+	if errors.Is(err, db.ErrI2CDeviceNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(device)
+}
+
+// updateI2CDevice godoc
+// @description Creates or replaces an I2C device.
+// @tags i2c
+// @url /api/i2c/devices/{name}
+func updateI2CDevice(c *fiber.Ctx) error {
+	var device dto.I2CDevice
+	if err := c.BodyParser(&device); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	device.Name = utils.CopyString(c.Params("name"))
+	if err := device.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	status, err := i2c.SetDevice(device)
+	if errors.Is(err, i2c.ErrInvalidDevice) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(status)
+}
+
+// deleteI2CDevice godoc
+// @description Removes an I2C device.
+// @tags i2c
+// @url /api/i2c/devices/{name}
+func deleteI2CDevice(c *fiber.Ctx) error {
+	err := i2c.DeleteDevice(c.Params("name"))
+	if errors.Is(err, db.ErrI2CDeviceNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "I2C device removed",
+	})
+}
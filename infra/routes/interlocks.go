@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getInterlocks godoc
+// @description Returns the groups of output pins of which at most one may be active.
+// @tags gpio
+// @url /api/gpio/interlocks
+func getInterlocks(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"interlocks": gpio.GetInterlocks(),
+	})
+}
+
+// updateInterlock godoc
+// @description Creates or replaces an interlock. It is refused if its pins are active together.
+// @tags gpio
+// @url /api/gpio/interlocks/{name}
+func updateInterlock(c *fiber.Ctx) error {
+	var interlock dto.Interlock
+	if err := c.BodyParser(&interlock); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	interlock.Name = utils.CopyString(c.Params("name"))
+	if err := interlock.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	interlock, err := gpio.SetInterlock(interlock)
+	if errors.Is(err, gpio.ErrInterlock) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if errors.Is(err, gpio.ErrInvalidInterlock) || errors.Is(err, gpio.ErrUnknownPin) || errors.Is(err, gpio.ErrAmbiguousPin) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(interlock)
+}
+
+// deleteInterlock godoc
+// @description Removes an interlock.
+// @tags gpio
+// @url /api/gpio/interlocks/{name}
+func deleteInterlock(c *fiber.Ctx) error {
+	err := gpio.DeleteInterlock(c.Params("name"))
+	if errors.Is(err, db.ErrInterlockNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Interlock removed",
+	})
+}
@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"strings"
+
 	"github.com/gabrielmoura/raspController/configs"
 	"github.com/gabrielmoura/raspController/infra/middleware"
+	"github.com/gabrielmoura/raspController/infra/prometheus"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/etag"
@@ -13,14 +18,21 @@ import (
 func InitializeRoutes(Fiber *fiber.App) {
 
 	Fiber.Use(cors.New())
-	Fiber.Use(etag.New())
+	Fiber.Use(etag.New(etag.Config{
+		// Event streams never end, so their body cannot be hashed.
+		Next: func(c *fiber.Ctx) bool {
+			return strings.HasSuffix(c.Path(), "/events")
+		},
+	}))
 	Fiber.Use(logger.New(logger.Config{
 		// For more options, see the Config section
 		Format:     "${pid} ${time} ${locals:requestid} ${status} - ${method} ${path}\n",
 		TimeFormat: configs.Conf.TimeFormat,
 		TimeZone:   configs.Conf.TimeZone,
 	}))
-	Fiber.Get("/metrics", monitor.New())
+	Fiber.Use(prometheus.CountRequests)
+	Fiber.Get("/metrics", middleware.Require(dto.ScopeInfoRead), monitor.New())
+	Fiber.Get("/metrics/prometheus", middleware.Require(dto.ScopeInfoRead), getPrometheus)
 
 	Fiber.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("RaspController API")
@@ -29,37 +41,135 @@ func InitializeRoutes(Fiber *fiber.App) {
 
 	api.Get("", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"/api/info":      "Returns system information.",
-			"/api/info/ps":   "Returns process information.",
-			"/api/info/net":  "Returns network information.",
-			"/api/info/mem":  "Returns memory information.",
-			"/api/info/disk": "Returns disk information.",
-			"/api/info/gpio": "Returns list of available GPIOs",
-			"/api/info/usb":  "Returns list of USB devices",
-			"/api/info/cpu":  "Returns CPU information.",
-			"/api/gpio":      "Returns the status of all configured GPIO pins.",
-			"/api/gpio/all":  "Returns all GPIO pins from the GPIO chip.",
-			"/api/share":     "Returns a list of files contained in the sharing directory.",
+			"/api/info":                "Returns system information.",
+			"/api/info/ps":             "Returns process information.",
+			"/api/info/net":            "Returns network information.",
+			"/api/info/mem":            "Returns memory information.",
+			"/api/info/disk":           "Returns disk information.",
+			"/api/info/gpio":           "Returns list of available GPIOs",
+			"/api/info/usb":            "Returns list of USB devices",
+			"/api/info/cpu":            "Returns CPU information.",
+			"/api/history":             "Returns the metrics of the history and their resolutions.",
+			"/api/history/:metric":     "Returns the past values of a metric.",
+			"/api/gpio":                "Returns the status of all configured GPIO pins, PATCH sets several at once.",
+			"/api/gpio/all":            "Returns all GPIO pins from the GPIO chip.",
+			"/api/gpio/restore":        "Returns the result of restoring the stored pins on startup.",
+			"/api/gpio/:pin":           "Returns the configured and measured status of a GPIO pin.",
+			"/api/gpio/:pin/events":    "Streams the edge events of an input pin (SSE or WebSocket).",
+			"/api/gpio/:pin/pulse":     "Pulses an output pin for a duration, optionally repeating.",
+			"/api/gpio/:pin/lease":     "Arms or removes the watchdog of an output pin.",
+			"/api/gpio/:pin/heartbeat": "Renews the watchdog of a pin.",
+			"/api/gpio/:pin/history":   "Returns the changes of a pin, newest first.",
+			"/api/audit":               "Returns the changes of every pin, newest first.",
+			"/api/gpio/:pin/simulate":  "Drives a pin of a simulated chip to a level (GPIO_DRIVER sim or gpio-sim).",
+			"/api/gpio/leases":         "Returns the watchdogs of every pin.",
+			"/api/gpio/interlocks":     "Manages the groups of output pins of which at most one may be active.",
+			"/api/pins":                "Returns the GPIO header of the board and the pin labels.",
+			"/api/pins/:pin":           "Sets or removes the label of a pin.",
+			"/api/pwm":                 "Returns all hardware PWM chips and their channels.",
+			"/api/pwm/restore":         "Returns the result of restoring the stored PWM channels on startup.",
+			"/api/pwm/:chip/:channel":  "Returns or configures a hardware PWM channel.",
+			"/api/rules":               "Manages the rules reacting to GPIO edges, metrics and schedules.",
+			"/api/rules/history":       "Returns the last rule firings.",
+			"/api/schedules":           "Manages the schedules configuring pins at cron times.",
+			"/api/alerts":              "Manages the alerts raised when a metric crosses a threshold.",
+			"/api/alerts/notifiers":    "Returns the notifiers of the alerts and sends test notifications.",
+			"/api/i2c":                 "Returns the I2C buses and the device drivers.",
+			"/api/i2c/:bus":            "Returns the addresses that answer on an I2C bus.",
+			"/api/i2c/:bus/:addr":      "Reads (GET) or writes (POST) the registers of an I2C device.",
+			"/api/i2c/devices":         "Manages the I2C devices and returns their decoded readings.",
+			"/api/mqtt":                "Returns the state of the MQTT bridge.",
+			"/api/tokens":              "Manages the API tokens (admin only).",
+			"/api/share":               "Returns a list of files contained in the sharing directory.",
 		})
 	})
 
-	api.Get("/info", middleware.CacheMiddleware(5), getInfo)
-	api.Get("/info/net", getNet)
-	api.Get("/info/mem", getMem)
-	api.Get("/info/disk", getDisk)
-	api.Get("/info/ps", getInfoProcess)
-	api.Get("/info/usb", getUsb)
-	api.Get("/info/cpu", middleware.CacheMiddleware(5), getCpu)
-	api.Get("/info/gpio", getGpioList)
-
-	api.Get("/gpio", getGpio)
-	api.Get("/gpio/all", middleware.CacheMiddleware(1), getGpioAll)
-	api.Patch("/gpio/:pin", updateGpio)
-
-	api.Get("/share", getShare)
-	api.Get("/share/*", getShareFile)
-	api.Delete("/share/*", middleware.CheckAuth, deleteShareFile)
-
-	api.Delete("/ps/:pid", middleware.CheckAuth, killProcess)
-	api.Get("/ps/:pid", getProcessByPid)
+	// Every route declares the scope it requires: write scopes always need a
+	// token, read scopes only when AUTH_READ is set.
+	require := middleware.Require
+
+	api.Get("/info", require(dto.ScopeInfoRead), middleware.CacheMiddleware(5), getInfo)
+	api.Get("/info/net", require(dto.ScopeInfoRead), getNet)
+	api.Get("/info/mem", require(dto.ScopeInfoRead), getMem)
+	api.Get("/info/disk", require(dto.ScopeInfoRead), getDisk)
+	api.Get("/info/ps", require(dto.ScopePsRead), getInfoProcess)
+	api.Get("/info/usb", require(dto.ScopeInfoRead), getUsb)
+	api.Get("/info/cpu", require(dto.ScopeInfoRead), middleware.CacheMiddleware(5), getCpu)
+	api.Get("/info/gpio", require(dto.ScopeGpioRead), getGpioList)
+
+	api.Get("/history", require(dto.ScopeInfoRead), getHistoryMetrics)
+	api.Get("/history/:metric", require(dto.ScopeInfoRead), getHistory)
+
+	api.Get("/gpio", require(dto.ScopeGpioRead), getGpio)
+	api.Patch("/gpio", require(dto.ScopeGpioWrite), updateGpioBatch)
+	api.Get("/gpio/all", require(dto.ScopeGpioRead), middleware.CacheMiddleware(1), getGpioAll)
+	api.Get("/gpio/restore", require(dto.ScopeGpioRead), getGpioRestore)
+	api.Get("/gpio/leases", require(dto.ScopeGpioRead), getLeases)
+	api.Get("/gpio/interlocks", require(dto.ScopeGpioRead), getInterlocks)
+	api.Put("/gpio/interlocks/:name", require(dto.ScopeGpioWrite), updateInterlock)
+	api.Delete("/gpio/interlocks/:name", require(dto.ScopeGpioWrite), deleteInterlock)
+	api.Get("/gpio/:pin", require(dto.ScopeGpioRead), getGpioPin)
+	api.Patch("/gpio/:pin", require(dto.ScopeGpioWrite), updateGpio)
+	api.Post("/gpio/:pin/pulse", require(dto.ScopeGpioWrite), pulseGpio)
+	api.Delete("/gpio/:pin/pulse", require(dto.ScopeGpioWrite), cancelPulseGpio)
+	api.Put("/gpio/:pin/lease", require(dto.ScopeGpioWrite), armLease)
+	api.Delete("/gpio/:pin/lease", require(dto.ScopeGpioWrite), disarmLease)
+	api.Post("/gpio/:pin/heartbeat", require(dto.ScopeGpioWrite), heartbeatLease)
+	api.Post("/gpio/:pin/simulate", require(dto.ScopeGpioWrite), simulateGpio)
+	api.Get("/gpio/:pin/history", require(dto.ScopeGpioRead), getGpioHistory)
+	api.Get("/gpio/:pin/events", require(dto.ScopeGpioRead), getGpioEvents, websocket.New(wsGpioEvents))
+
+	api.Get("/audit", require(dto.ScopeGpioRead), getAudit)
+
+	api.Get("/pins", require(dto.ScopeGpioRead), getPins)
+	api.Put("/pins/:pin", require(dto.ScopeGpioWrite), updatePinLabel)
+	api.Delete("/pins/:pin", require(dto.ScopeGpioWrite), deletePinLabel)
+
+	api.Get("/pwm", require(dto.ScopePwmRead), getPwm)
+	api.Get("/pwm/restore", require(dto.ScopePwmRead), getPwmRestore)
+	api.Get("/pwm/:chip/:channel", require(dto.ScopePwmRead), getPwmChannel)
+	api.Patch("/pwm/:chip/:channel", require(dto.ScopePwmWrite), updatePwmChannel)
+
+	api.Get("/share", require(dto.ScopeShareRead), getShare)
+	api.Get("/share/*", require(dto.ScopeShareRead), getShareFile)
+	api.Delete("/share/*", require(dto.ScopeShareWrite), deleteShareFile)
+
+	api.Delete("/ps/:pid", require(dto.ScopePsKill), killProcess)
+	api.Get("/ps/:pid", require(dto.ScopePsRead), getProcessByPid)
+
+	api.Get("/rules", require(dto.ScopeRulesRead), getRules)
+	api.Post("/rules", require(dto.ScopeRulesWrite), createRule)
+	api.Get("/rules/history", require(dto.ScopeRulesRead), getRuleHistory)
+	api.Get("/rules/:name", require(dto.ScopeRulesRead), getRule)
+	api.Put("/rules/:name", require(dto.ScopeRulesWrite), updateRule)
+	api.Delete("/rules/:name", require(dto.ScopeRulesWrite), deleteRule)
+	api.Get("/rules/:name/history", require(dto.ScopeRulesRead), getRuleHistory)
+
+	api.Get("/schedules", require(dto.ScopeGpioRead), getSchedules)
+	api.Post("/schedules", require(dto.ScopeGpioWrite), createSchedule)
+	api.Get("/schedules/:name", require(dto.ScopeGpioRead), getSchedule)
+	api.Put("/schedules/:name", require(dto.ScopeGpioWrite), updateSchedule)
+	api.Delete("/schedules/:name", require(dto.ScopeGpioWrite), deleteSchedule)
+
+	api.Get("/alerts", require(dto.ScopeRulesRead), getAlerts)
+	api.Get("/alerts/notifiers", require(dto.ScopeRulesRead), getNotifiers)
+	api.Post("/alerts/notifiers/:name/test", require(dto.ScopeRulesWrite), testNotifier)
+	api.Get("/alerts/:name", require(dto.ScopeRulesRead), getAlert)
+	api.Put("/alerts/:name", require(dto.ScopeRulesWrite), updateAlert)
+	api.Delete("/alerts/:name", require(dto.ScopeRulesWrite), deleteAlert)
+
+	api.Get("/i2c", require(dto.ScopeI2CRead), getI2C)
+	api.Get("/i2c/devices", require(dto.ScopeI2CRead), getI2CDevices)
+	api.Get("/i2c/devices/:name", require(dto.ScopeI2CRead), getI2CDevice)
+	api.Put("/i2c/devices/:name", require(dto.ScopeI2CWrite), updateI2CDevice)
+	api.Delete("/i2c/devices/:name", require(dto.ScopeI2CWrite), deleteI2CDevice)
+	api.Get("/i2c/:bus", require(dto.ScopeI2CRead), scanI2C)
+	api.Get("/i2c/:bus/:addr", require(dto.ScopeI2CRead), readI2C)
+	api.Post("/i2c/:bus/:addr", require(dto.ScopeI2CWrite), writeI2C)
+
+	api.Get("/mqtt", require(dto.ScopeInfoRead), getMQTT)
+
+	api.Get("/tokens", require(dto.ScopeAdmin), getTokens)
+	api.Post("/tokens", require(dto.ScopeAdmin), createToken)
+	api.Delete("/tokens/:name", require(dto.ScopeAdmin), deleteToken)
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gabrielmoura/raspController/infra/mqtt"
+	"github.com/gofiber/fiber/v2"
+)
+
+// getMQTT godoc
+// @description Returns whether the MQTT bridge is enabled and connected, its broker and base topic.
+// @tags mqtt
+// @url /api/mqtt
+func getMQTT(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(mqtt.GetStatus())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gabrielmoura/raspController/infra/prometheus"
+	"github.com/gofiber/fiber/v2"
+)
+
+// getPrometheus godoc
+// @description Returns the system, GPIO and HTTP metrics in the Prometheus text format.
+// @tags info
+// @url /metrics/prometheus
+func getPrometheus(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, prometheus.ContentType)
+	return c.Send(prometheus.Scrape())
+}
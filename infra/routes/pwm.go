@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/pwm"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// getPwm godoc
+// @description Returns all hardware PWM chips and the state of their channels.
+// @tags pwm
+// @url /api/pwm
+func getPwm(c *fiber.Ctx) error {
+	list, err := pwm.GetAll()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"chips": list,
+	})
+}
+
+// getPwmRestore godoc
+// @description Returns the result of restoring the stored PWM channels on startup.
+// @tags pwm
+// @url /api/pwm/restore
+func getPwmRestore(c *fiber.Ctx) error {
+	if !pwm.CheckSysfs() {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "PWM not initialized",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pwm.GetRestoreReport())
+}
+
+// getPwmChannel godoc
+// @description Returns the state of a hardware PWM channel.
+// @tags pwm
+// @url /api/pwm/{chip}/{channel}
+func getPwmChannel(c *fiber.Ctx) error {
+	chip, err := c.ParamsInt("chip")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	channel, err := c.ParamsInt("channel")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	state, err := pwm.GetChannel(chip, channel)
+	if errors.Is(err, pwm.ErrChannelNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(state)
+}
+
+// updatePwmChannel godoc
+// @description Configures a hardware PWM channel.
+// @tags pwm
+// @url /api/pwm/{chip}/{channel}
+func updatePwmChannel(c *fiber.Ctx) error {
+	var ch dto.PWMChannel
+	err := c.BodyParser(&ch)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ch.Chip, err = c.ParamsInt("chip")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	ch.Channel, err = c.ParamsInt("channel")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := ch.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = pwm.Set(ch)
+	if errors.Is(err, pwm.ErrChannelNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(ch)
+}
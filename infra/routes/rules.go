@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/middleware"
+	"github.com/gabrielmoura/raspController/infra/rules"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getRules godoc
+// @description Returns all rules with the state of their triggers.
+// @tags rules
+// @url /api/rules
+func getRules(c *fiber.Ctx) error {
+	list, err := rules.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"rules": list,
+	})
+}
+
+// getRule godoc
+// @description Returns a rule with the state of its trigger.
+// @tags rules
+// @url /api/rules/{name}
+func getRule(c *fiber.Ctx) error {
+	rule, err := rules.Get(c.Params("name"))
+	if errors.Is(err, db.ErrRuleNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rule)
+}
+
+// createRule godoc
+// @description Creates a rule and starts watching its trigger.
+// @tags rules
+// @url /api/rules
+func createRule(c *fiber.Ctx) error {
+	var rule dto.Rule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return saveRule(c, rule, rules.Create, fiber.StatusCreated)
+}
+
+// updateRule godoc
+// @description Replaces a rule and restarts its trigger.
+// @tags rules
+// @url /api/rules/{name}
+func updateRule(c *fiber.Ctx) error {
+	var rule dto.Rule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	rule.Name = utils.CopyString(c.Params("name"))
+	return saveRule(c, rule, rules.Update, fiber.StatusOK)
+}
+
+// saveRule validates rule and stores it with save.
+func saveRule(c *fiber.Ctx, rule dto.Rule, save func(dto.Rule) error, status int) error {
+	if err := rule.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// Actions run with the rights of the service, so saving them needs the
+	// scopes the same changes would need through the API.
+	for i := range rule.Actions {
+		if scope := rule.Actions[i].Scope(); scope != "" && !middleware.Granted(c, scope) {
+			return middleware.Forbidden(c, fmt.Sprintf("action %d requires scope %s", i+1, scope))
+		}
+	}
+
+	err := save(rule)
+	switch {
+	case errors.Is(err, rules.ErrInvalidRule):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, rules.ErrRuleExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, db.ErrRuleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(status).JSON(rule)
+}
+
+// deleteRule godoc
+// @description Stops and removes a rule. Its firings stay in the history.
+// @tags rules
+// @url /api/rules/{name}
+func deleteRule(c *fiber.Ctx) error {
+	err := rules.Delete(c.Params("name"))
+	if errors.Is(err, db.ErrRuleNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Rule deleted",
+	})
+}
+
+// getRuleHistory godoc
+// @description Returns the last firings of every rule, or of one rule, newest first.
+// @tags rules
+// @url /api/rules/history?limit=100
+// @url /api/rules/{name}/history?limit=100
+func getRuleHistory(c *fiber.Ctx) error {
+	history, err := rules.History(c.Params("name"), c.QueryInt("limit", 100))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"history": history,
+	})
+}
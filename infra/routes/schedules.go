@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/scheduler"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// getSchedules godoc
+// @description Returns all schedules with their next and last run.
+// @tags schedules
+// @url /api/schedules
+func getSchedules(c *fiber.Ctx) error {
+	list, err := scheduler.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"schedules": list,
+	})
+}
+
+// getSchedule godoc
+// @description Returns a schedule with its next and last run.
+// @tags schedules
+// @url /api/schedules/{name}
+func getSchedule(c *fiber.Ctx) error {
+	schedule, err := scheduler.Get(c.Params("name"))
+	if errors.Is(err, db.ErrScheduleNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(schedule)
+}
+
+// createSchedule godoc
+// @description Creates a schedule configuring pins at the times of a cron expression.
+// @tags schedules
+// @url /api/schedules
+func createSchedule(c *fiber.Ctx) error {
+	var schedule dto.Schedule
+	if err := c.BodyParser(&schedule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return saveSchedule(c, schedule, scheduler.Create, fiber.StatusCreated)
+}
+
+// updateSchedule godoc
+// @description Replaces a schedule.
+// @tags schedules
+// @url /api/schedules/{name}
+func updateSchedule(c *fiber.Ctx) error {
+	var schedule dto.Schedule
+	if err := c.BodyParser(&schedule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	schedule.Name = utils.CopyString(c.Params("name"))
+	return saveSchedule(c, schedule, scheduler.Update, fiber.StatusOK)
+}
+
+// saveSchedule validates schedule and stores it with save.
+func saveSchedule(c *fiber.Ctx, schedule dto.Schedule, save func(dto.Schedule) error, status int) error {
+	if err := schedule.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err := save(schedule)
+	switch {
+	case errors.Is(err, scheduler.ErrInvalidCron):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, scheduler.ErrScheduleExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, db.ErrScheduleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(status).JSON(schedule)
+}
+
+// deleteSchedule godoc
+// @description Removes a schedule.
+// @tags schedules
+// @url /api/schedules/{name}
+func deleteSchedule(c *fiber.Ctx) error {
+	err := scheduler.Delete(c.Params("name"))
+	if errors.Is(err, db.ErrScheduleNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Schedule deleted",
+	})
+}
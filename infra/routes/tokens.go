@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gabrielmoura/raspController/infra/auth"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// getTokens godoc
+// @description Returns all API tokens, without their secrets.
+// @tags tokens
+// @url /api/tokens
+func getTokens(c *fiber.Ctx) error {
+	list, err := auth.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"tokens": list,
+	})
+}
+
+// createToken godoc
+// @description Creates an API token. The secret is only returned in this response.
+// @tags tokens
+// @url /api/tokens
+func createToken(c *fiber.Ctx) error {
+	var req dto.TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := req.Validation(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	secret, token, err := auth.Create(req)
+	if errors.Is(err, auth.ErrTokenExists) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"token":  token,
+		"secret": secret,
+	})
+}
+
+// deleteToken godoc
+// @description Revokes an API token.
+// @tags tokens
+// @url /api/tokens/{name}
+func deleteToken(c *fiber.Ctx) error {
+	err := auth.Delete(c.Params("name"))
+	if errors.Is(err, db.ErrTokenNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Token deleted",
+	})
+}
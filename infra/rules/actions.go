@@ -0,0 +1,87 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+// webhookClient posts the firings of webhook actions.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// run executes a single action of a firing rule.
+func run(action dto.RuleAction, firing dto.RuleFiring) error {
+	switch action.Type {
+	case dto.ActionSet:
+		line, err := gpio.ResolvePin(action.Pin)
+		if err != nil {
+			return err
+		}
+		mode := *action.Mode
+		mode.Chip, mode.Pin = line.Chip, line.Offset
+		return gpio.SetBool(mode, dto.Source{Type: dto.SourceRule, Name: firing.Rule})
+	case dto.ActionPulse:
+		line, err := gpio.ResolvePin(action.Pin)
+		if err != nil {
+			return err
+		}
+		return gpio.Pulse(line, *action.Pulse, dto.Source{Type: dto.SourceRule, Name: firing.Rule})
+	case dto.ActionKill:
+		return kill(action)
+	case dto.ActionWebhook:
+		return webhook(action.URL, firing)
+	default:
+		return fmt.Errorf("unknown action %q", action.Type)
+	}
+}
+
+// kill terminates the process of the action, or every process running its command.
+func kill(action dto.RuleAction) error {
+	if action.PID > 0 {
+		return exec.Command("kill", strconv.Itoa(action.PID)).Run()
+	}
+
+	processes, err := vchiq.ListProcesses()
+	if err != nil {
+		return err
+	}
+	killed := 0
+	for _, p := range processes {
+		if p.Cmd != action.Process {
+			continue
+		}
+		if err := exec.Command("kill", p.PID).Run(); err != nil {
+			return fmt.Errorf("error killing %s (%s): %w", p.Cmd, p.PID, err)
+		}
+		killed++
+	}
+	if killed == 0 {
+		return fmt.Errorf("%w: %s", vchiq.ErrProcessNotFound, action.Process)
+	}
+	return nil
+}
+
+// webhook posts the firing as JSON to url.
+func webhook(url string, firing dto.RuleFiring) error {
+	body, err := json.Marshal(firing)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package rules runs user defined rules that act on GPIO edges, system
+// metrics and schedules without a polling client.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// ErrInvalidRule is returned when a rule references a metric, pin or schedule that does not exist.
+	ErrInvalidRule = errors.New("invalid rule")
+	// ErrRuleExists is returned when creating a rule whose name is taken.
+	ErrRuleExists = errors.New("rule already exists")
+)
+
+var (
+	runners   = make(map[string]*runner) // keyed by rule name
+	scheduler *cron.Cron
+	mu        sync.Mutex // guards runners and the rule list in the database
+	historyMu sync.Mutex // guards the rule history in the database
+	once      sync.Once
+)
+
+// RuleState is a rule together with the state of its trigger.
+type RuleState struct {
+	dto.Rule
+	Running   bool       `json:"running"`
+	Error     string     `json:"error,omitempty"` // why the trigger is not running
+	LastFired *time.Time `json:"last_fired"`
+}
+
+// Initialize starts every enabled rule stored in the database.
+func Initialize(ctx context.Context) error {
+	var initErr error
+	once.Do(func() {
+		scheduler = cron.New(cron.WithLocation(configs.Conf.Location()))
+		scheduler.Start()
+
+		stored, err := db.GetRules()
+		if err != nil {
+			initErr = fmt.Errorf("Error reading rules: %w", err)
+			return
+		}
+
+		mu.Lock()
+		for _, rule := range stored {
+			start(rule)
+		}
+		mu.Unlock()
+		log.Printf("Rules: %d rules loaded", len(stored))
+
+		go func() {
+			<-ctx.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for name := range runners {
+				stop(name)
+			}
+			scheduler.Stop()
+		}()
+	})
+	return initErr
+}
+
+// check validates the parts of rule that depend on the system.
+func check(rule dto.Rule) error {
+	switch rule.Trigger.Type {
+	case dto.TriggerEdge:
+		if _, err := gpio.ResolvePin(rule.Trigger.Pin); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	case dto.TriggerMetric:
+		valid := false
+		for _, m := range vchiq.Metrics {
+			valid = valid || m == rule.Trigger.Metric
+		}
+		if !valid {
+			return fmt.Errorf("%w: %w: %s", ErrInvalidRule, vchiq.ErrUnknownMetric, rule.Trigger.Metric)
+		}
+	case dto.TriggerSchedule:
+		if _, err := cron.ParseStandard(rule.Trigger.Cron); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	for _, action := range rule.Actions {
+		if action.Type == dto.ActionSet || action.Type == dto.ActionPulse {
+			if _, err := gpio.ResolvePin(action.Pin); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+			}
+		}
+	}
+	return nil
+}
+
+// List returns every rule with its state, sorted by name.
+func List() ([]RuleState, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetRules()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]RuleState, 0, len(stored))
+	for _, rule := range stored {
+		list = append(list, state(rule))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns a rule with its state.
+func Get(name string) (RuleState, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetRules()
+	if err != nil {
+		return RuleState{}, err
+	}
+	rule, ok := stored[name]
+	if !ok {
+		return RuleState{}, db.ErrRuleNotFound
+	}
+	return state(rule), nil
+}
+
+// state returns the state of rule.
+// The caller must hold mu.
+func state(rule dto.Rule) RuleState {
+	s := RuleState{Rule: rule}
+	if r, ok := runners[rule.Name]; ok {
+		s.Running, s.Error, s.LastFired = r.status()
+	}
+	return s
+}
+
+// Create stores a new rule and starts it.
+func Create(rule dto.Rule) error {
+	return set(rule, true)
+}
+
+// Update replaces a stored rule and restarts it.
+func Update(rule dto.Rule) error {
+	return set(rule, false)
+}
+
+func set(rule dto.Rule, create bool) error {
+	if err := check(rule); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetRules()
+	if err != nil {
+		return err
+	}
+	_, exists := stored[rule.Name]
+	if create && exists {
+		return ErrRuleExists
+	} else if !create && !exists {
+		return db.ErrRuleNotFound
+	}
+
+	if err := db.SetRule(rule); err != nil {
+		return err
+	}
+	stop(rule.Name)
+	start(rule)
+	return nil
+}
+
+// Delete stops and removes a rule. Its firings are kept in the history.
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := db.DeleteRule(name); err != nil {
+		return err
+	}
+	stop(name)
+	return nil
+}
+
+// History returns the last limit firings, newest first, of the rule name or of
+// every rule if name is empty.
+func History(name string, limit int) ([]dto.RuleFiring, error) {
+	historyMu.Lock()
+	all, err := db.GetRuleHistory()
+	historyMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]dto.RuleFiring, 0)
+	for i := len(all) - 1; i >= 0 && (limit <= 0 || len(history) < limit); i-- {
+		if name == "" || all[i].Rule == name {
+			history = append(history, all[i])
+		}
+	}
+	return history, nil
+}
+
+// record stores a firing in the history.
+func record(firing dto.RuleFiring) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if err := db.AddRuleFiring(firing); err != nil {
+		log.Printf("Rules: Error recording firing of %s: %v", firing.Rule, err)
+	}
+}
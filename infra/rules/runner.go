@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+	"github.com/robfig/cron/v3"
+)
+
+// resubscribeDelay is how long an edge trigger waits before watching its pin
+// again after the subscription ended, e.g. because the pin was reconfigured.
+const resubscribeDelay = 5 * time.Second
+
+// runner watches the trigger of a rule and fires its actions.
+type runner struct {
+	rule     dto.Rule
+	cooldown time.Duration
+	stopCh   chan struct{}
+	done     chan struct{}
+	entry    cron.EntryID // schedule triggers only
+
+	mu        sync.Mutex // guards the fields below
+	err       string
+	lastFired *time.Time
+	firing    bool
+}
+
+// start runs the trigger of rule unless it is disabled.
+// The caller must hold mu.
+func start(rule dto.Rule) {
+	if rule.Disabled {
+		return
+	}
+	cooldown, _ := rule.CooldownPeriod()
+	r := &runner{rule: rule, cooldown: cooldown, stopCh: make(chan struct{}), done: make(chan struct{})}
+	runners[rule.Name] = r
+
+	switch rule.Trigger.Type {
+	case dto.TriggerEdge:
+		go r.watchEdges()
+	case dto.TriggerMetric:
+		go r.watchMetric()
+	case dto.TriggerSchedule:
+		close(r.done)
+		entry, err := scheduler.AddFunc(rule.Trigger.Cron, func() { r.fire("schedule " + rule.Trigger.Cron) })
+		if err != nil {
+			r.setError(err)
+			return
+		}
+		r.entry = entry
+	}
+}
+
+// stop ends the trigger of the rule name, if running.
+// The caller must hold mu.
+func stop(name string) {
+	r, ok := runners[name]
+	if !ok {
+		return
+	}
+	delete(runners, name)
+	if r.entry != 0 {
+		scheduler.Remove(r.entry)
+	}
+	close(r.stopCh)
+	<-r.done
+}
+
+func (r *runner) status() (running bool, err string, lastFired *time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err == "", r.err, r.lastFired
+}
+
+func (r *runner) setError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.err = ""
+		return
+	}
+	r.err = err.Error()
+}
+
+// stopped reports whether the runner was stopped.
+func (r *runner) stopped() bool {
+	select {
+	case <-r.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait sleeps for d, returning false if the runner was stopped.
+func (r *runner) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.stopCh:
+		return false
+	}
+}
+
+// watchEdges fires the rule on every matching edge of the trigger pin.
+func (r *runner) watchEdges() {
+	defer close(r.done)
+	trigger := r.rule.Trigger
+	sub := dto.EventSubscription{Edge: trigger.Edge, Debounce: trigger.Debounce}
+
+	for !r.stopped() {
+		line, err := gpio.ResolvePin(trigger.Pin)
+		if err != nil {
+			r.setError(err)
+			if !r.wait(resubscribeDelay) {
+				return
+			}
+			continue
+		}
+		events, unsubscribe, err := gpio.Subscribe(line, sub)
+		if err != nil {
+			r.setError(err)
+			if !r.wait(resubscribeDelay) {
+				return
+			}
+			continue
+		}
+		r.setError(nil)
+
+		r.consume(events)
+		unsubscribe()
+		if !r.wait(resubscribeDelay) {
+			return
+		}
+	}
+}
+
+// consume fires the rule for each event until the channel is closed or the runner stops.
+func (r *runner) consume(events <-chan gpio.Event) {
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			// Actions run apart so that a slow webhook does not fill the event buffer.
+			go r.fire(fmt.Sprintf("%s edge on %s:%d", ev.Edge, ev.Chip, ev.Pin))
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// watchMetric fires the rule when the comparison of the metric becomes true.
+func (r *runner) watchMetric() {
+	defer close(r.done)
+	trigger := r.rule.Trigger
+	interval, _ := trigger.IntervalPeriod()
+
+	met := false
+	for {
+		value, err := vchiq.ReadMetric(trigger.Metric)
+		r.setError(err)
+		if err == nil {
+			now := trigger.Compare(value)
+			if now && !met {
+				go r.fire(fmt.Sprintf("%s %s %g (%g)", trigger.Metric, trigger.Operator, trigger.Threshold, value))
+			}
+			met = now
+		}
+		if !r.wait(interval) {
+			return
+		}
+	}
+}
+
+// fire runs the actions of the rule and records the firing, unless the rule
+// is in its cooldown or still running a previous firing.
+func (r *runner) fire(cause string) {
+	now := time.Now()
+	r.mu.Lock()
+	if r.firing || (r.lastFired != nil && now.Sub(*r.lastFired) < r.cooldown) {
+		r.mu.Unlock()
+		return
+	}
+	r.firing = true
+	r.lastFired = &now
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.firing = false
+		r.mu.Unlock()
+	}()
+
+	firing := dto.RuleFiring{Rule: r.rule.Name, Time: now, Trigger: cause}
+	for _, action := range r.rule.Actions {
+		result := dto.RuleActionResult{Type: action.Type}
+		if err := run(action, firing); err != nil {
+			result.Error = err.Error()
+			log.Printf("Rules: %s: %s action failed: %v", r.rule.Name, action.Type, err)
+		}
+		firing.Actions = append(firing.Actions, result)
+	}
+	log.Printf("Rules: %s fired by %s", r.rule.Name, cause)
+	record(firing)
+}
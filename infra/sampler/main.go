@@ -0,0 +1,88 @@
+// Package sampler records the system metrics in the history of the database
+// at a fixed interval, so that past values can be queried.
+package sampler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/pkg/vchiq"
+)
+
+const (
+	flushEvery = time.Minute      // interval between two stores of the steps being aggregated
+	pruneEvery = 10 * time.Minute // interval between two removals of the expired samples
+)
+
+var once sync.Once
+
+// Initialize starts sampling every metric of vchiq.Metrics each
+// SAMPLE_INTERVAL, unless it is zero.
+func Initialize(ctx context.Context) error {
+	interval := configs.Conf.SampleInterval
+	if interval <= 0 {
+		log.Println("Sampler: disabled")
+		return nil
+	}
+	once.Do(func() {
+		go run(ctx, interval)
+		log.Printf("Sampler: sampling %d metrics every %s", len(vchiq.Metrics), interval)
+	})
+	return nil
+}
+
+func run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastFlush, lastPrune := time.Now(), time.Now()
+	failing := make(map[string]string) // last error of each metric, logged once
+
+	sample(failing)
+	for {
+		select {
+		case <-ticker.C:
+			sample(failing)
+			if time.Since(lastFlush) >= flushEvery {
+				if err := db.FlushSamples(); err != nil {
+					log.Println("Sampler: Error storing the history:", err)
+				}
+				lastFlush = time.Now()
+			}
+			if time.Since(lastPrune) >= pruneEvery {
+				if err := db.PruneHistory(); err != nil {
+					log.Println("Sampler: Error pruning the history:", err)
+				}
+				lastPrune = time.Now()
+			}
+		case <-ctx.Done():
+			if err := db.FlushSamples(); err != nil {
+				log.Println("Sampler: Error storing the history:", err)
+			}
+			return
+		}
+	}
+}
+
+// sample reads and records every metric. A metric that cannot be read, such
+// as the vcgencmd ones on other boards, is skipped.
+func sample(failing map[string]string) {
+	now := time.Now()
+	for _, metric := range vchiq.Metrics {
+		value, err := vchiq.ReadMetric(metric)
+		if err != nil {
+			if failing[metric] != err.Error() {
+				log.Printf("Sampler: Error reading %s: %v", metric, err)
+				failing[metric] = err.Error()
+			}
+			continue
+		}
+		delete(failing, metric)
+		if err := db.AddSample(metric, now, value); err != nil {
+			log.Printf("Sampler: Error recording %s: %v", metric, err)
+		}
+	}
+}
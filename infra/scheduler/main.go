@@ -0,0 +1,254 @@
+// Package scheduler configures GPIO pins at the times of cron expressions.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabrielmoura/raspController/configs"
+	"github.com/gabrielmoura/raspController/infra/db"
+	"github.com/gabrielmoura/raspController/infra/gpio"
+	"github.com/gabrielmoura/raspController/internal/dto"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// ErrInvalidCron is returned when the cron expression of a schedule cannot be parsed.
+	ErrInvalidCron = errors.New("invalid cron expression")
+	// ErrScheduleExists is returned when creating a schedule whose name is taken.
+	ErrScheduleExists = errors.New("schedule already exists")
+)
+
+var (
+	scheduler *cron.Cron
+	entries   = make(map[string]cron.EntryID) // keyed by schedule name
+	mu        sync.Mutex                      // guards entries and the schedules in the database
+	runMu     sync.Mutex                      // guards the schedule runs in the database
+	once      sync.Once
+)
+
+// ScheduleState is a schedule together with its next and past runs.
+type ScheduleState struct {
+	dto.Schedule
+	dto.ScheduleRun
+	NextRun *time.Time `json:"next_run"`
+}
+
+// Initialize starts every enabled schedule stored in the database. Runs missed
+// while the service was down are not replayed.
+func Initialize(ctx context.Context) error {
+	var initErr error
+	once.Do(func() {
+		scheduler = cron.New(cron.WithLocation(configs.Conf.Location()))
+
+		stored, err := db.GetSchedules()
+		if err != nil {
+			initErr = fmt.Errorf("Error reading schedules: %w", err)
+			return
+		}
+
+		mu.Lock()
+		for _, schedule := range stored {
+			if err := start(schedule); err != nil {
+				log.Printf("Scheduler: Error starting %s: %v", schedule.Name, err)
+			}
+		}
+		mu.Unlock()
+
+		scheduler.Start()
+		log.Printf("Scheduler: %d schedules loaded", len(stored))
+
+		go func() {
+			<-ctx.Done()
+			<-scheduler.Stop().Done()
+		}()
+	})
+	return initErr
+}
+
+// parse checks the cron expression of a schedule.
+func parse(expr string) error {
+	if _, err := cron.ParseStandard(expr); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCron, err)
+	}
+	return nil
+}
+
+// start adds schedule to the scheduler unless it is disabled.
+// The caller must hold mu.
+func start(schedule dto.Schedule) error {
+	if schedule.Disabled {
+		return nil
+	}
+	id, err := scheduler.AddFunc(schedule.Cron, func() { run(schedule) })
+	if err != nil {
+		return err
+	}
+	entries[schedule.Name] = id
+	return nil
+}
+
+// stop removes the schedule name from the scheduler, if running.
+// The caller must hold mu.
+func stop(name string) {
+	if id, ok := entries[name]; ok {
+		scheduler.Remove(id)
+		delete(entries, name)
+	}
+}
+
+// run applies the actions of schedule and records the outcome.
+func run(schedule dto.Schedule) {
+	var errs []string
+	for _, action := range schedule.Actions {
+		if action.Chip == "" {
+			action.Chip = gpio.DefaultLine(action.Pin).Chip
+		}
+		if err := gpio.SetBool(action, dto.Source{Type: dto.SourceSchedule, Name: schedule.Name}); err != nil {
+			errs = append(errs, fmt.Sprintf("pin %s: %v", action.Line(), err))
+		}
+	}
+
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	// Delete may have removed the schedule while its actions were applied.
+	// Its run is then dropped rather than left behind for a new schedule of
+	// the same name.
+	stored, err := db.GetSchedules()
+	if err != nil {
+		log.Printf("Scheduler: Error reading schedules: %v", err)
+		return
+	}
+	if _, ok := stored[schedule.Name]; !ok {
+		return
+	}
+	runs, err := db.GetScheduleRuns()
+	if err != nil {
+		log.Printf("Scheduler: Error reading runs of %s: %v", schedule.Name, err)
+		return
+	}
+	now := time.Now().In(scheduler.Location())
+	r := runs[schedule.Name]
+	r.LastRun = &now
+	r.LastError = strings.Join(errs, "; ")
+	r.Runs++
+	if len(errs) > 0 {
+		r.Failures++
+		log.Printf("Scheduler: %s failed: %s", schedule.Name, r.LastError)
+	} else {
+		log.Printf("Scheduler: %s ran", schedule.Name)
+	}
+	if err := db.SetScheduleRun(schedule.Name, r); err != nil {
+		log.Printf("Scheduler: Error recording run of %s: %v", schedule.Name, err)
+	}
+}
+
+// List returns every schedule with its runs, sorted by name.
+func List() ([]ScheduleState, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetSchedules()
+	if err != nil {
+		return nil, err
+	}
+	runs, err := db.GetScheduleRuns()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ScheduleState, 0, len(stored))
+	for _, schedule := range stored {
+		list = append(list, state(schedule, runs[schedule.Name]))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns a schedule with its runs.
+func Get(name string) (ScheduleState, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetSchedules()
+	if err != nil {
+		return ScheduleState{}, err
+	}
+	schedule, ok := stored[name]
+	if !ok {
+		return ScheduleState{}, db.ErrScheduleNotFound
+	}
+	runs, err := db.GetScheduleRuns()
+	if err != nil {
+		return ScheduleState{}, err
+	}
+	return state(schedule, runs[name]), nil
+}
+
+// state returns the state of schedule.
+// The caller must hold mu.
+func state(schedule dto.Schedule, run dto.ScheduleRun) ScheduleState {
+	s := ScheduleState{Schedule: schedule, ScheduleRun: run}
+	if id, ok := entries[schedule.Name]; ok {
+		if next := scheduler.Entry(id).Next; !next.IsZero() {
+			s.NextRun = &next
+		}
+	}
+	return s
+}
+
+// Create stores a new schedule and starts it.
+func Create(schedule dto.Schedule) error {
+	return set(schedule, true)
+}
+
+// Update replaces a stored schedule and restarts it.
+func Update(schedule dto.Schedule) error {
+	return set(schedule, false)
+}
+
+func set(schedule dto.Schedule, create bool) error {
+	if err := parse(schedule.Cron); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := db.GetSchedules()
+	if err != nil {
+		return err
+	}
+	_, exists := stored[schedule.Name]
+	if create && exists {
+		return ErrScheduleExists
+	} else if !create && !exists {
+		return db.ErrScheduleNotFound
+	}
+
+	if err := db.SetSchedule(schedule); err != nil {
+		return err
+	}
+	stop(schedule.Name)
+	return start(schedule)
+}
+
+// Delete stops and removes a schedule.
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	if err := db.DeleteSchedule(name); err != nil {
+		return err
+	}
+	stop(name)
+	return nil
+}
@@ -1,29 +1,993 @@
 package dto
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line identifies a GPIO line by the name of its chip and its offset on it.
+type Line struct {
+	Chip   string `json:"chip"`
+	Offset int    `json:"offset"`
+}
+
+// String returns the line as "chip:offset".
+func (l Line) String() string {
+	return l.Chip + ":" + strconv.Itoa(l.Offset)
+}
 
 type PinMode struct {
+	Chip      string `json:"chip"` // empty for the default chip
 	Pin       int    `json:"pin"`
 	Value     int    `json:"value"`
-	Direction string `json:"direction"` // in or out
+	Direction string `json:"direction"` // in, out or pwm
 	Active    string `json:"active"`    // low or hight
+	Bias      string `json:"bias"`      // pull-up, pull-down or disabled
+	Drive     string `json:"drive"`     // push-pull, open-drain or open-source
+	Restore   string `json:"restore"`   // last, safe or none
+	SafeValue int    `json:"safe_value"`
+
+	// Software PWM settings, only used when Direction is pwm.
+	Frequency float64 `json:"frequency,omitempty"`  // Hz
+	DutyCycle float64 `json:"duty_cycle,omitempty"` // percent, 0 to 100
+}
+
+// Line returns the line the pin refers to.
+func (p *PinMode) Line() Line {
+	return Line{Chip: p.Chip, Offset: p.Pin}
 }
 
 // Valid direction and activation constants.
 const (
 	Input  = "in"
 	Output = "out"
+	PWM    = "pwm"
 	Low    = "low"
 	High   = "high"
 )
 
+// MaxSoftPWMFrequency is the highest frequency the software PWM can keep up with.
+const MaxSoftPWMFrequency = 1000
+
+// Valid bias constants.
+const (
+	PullUp       = "pull-up"
+	PullDown     = "pull-down"
+	BiasDisabled = "disabled"
+)
+
+// Valid output drive constants.
+const (
+	PushPull   = "push-pull"
+	OpenDrain  = "open-drain"
+	OpenSource = "open-source"
+)
+
+// Valid restore on boot policies.
+const (
+	RestoreLast = "last" // replay the last stored value
+	RestoreSafe = "safe" // drive the pin to SafeValue
+	RestoreNone = "none" // leave the pin untouched
+)
+
+// Valid edge detection constants.
+const (
+	EdgeRising  = "rising"
+	EdgeFalling = "falling"
+	EdgeBoth    = "both"
+)
+
+// EventSubscription holds the parameters of a GPIO edge event subscription.
+type EventSubscription struct {
+	Edge     string `query:"edge"`     // rising, falling or both
+	Debounce string `query:"debounce"` // e.g. 10ms
+}
+
+// Validation validates the EventSubscription structure.
+func (e *EventSubscription) Validation() error {
+	if len(e.Edge) > 0 && e.Edge != EdgeRising && e.Edge != EdgeFalling && e.Edge != EdgeBoth {
+		return errors.New("invalid edge, use 'rising', 'falling' or 'both'")
+	}
+	if _, err := e.DebouncePeriod(); err != nil {
+		return errors.New("invalid debounce period, use a duration such as '10ms'")
+	}
+	return nil
+}
+
+// DebouncePeriod returns the parsed debounce period, zero if not set.
+func (e *EventSubscription) DebouncePeriod() (time.Duration, error) {
+	if len(e.Debounce) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(e.Debounce)
+	if err == nil && d < 0 {
+		err = errors.New("negative debounce period")
+	}
+	return d, err
+}
+
+var labelRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{0,63}$`)
+
+// PinLabel gives a human name, description and tags to a pin.
+type PinLabel struct {
+	Chip        string   `json:"chip"`
+	Pin         int      `json:"pin"`
+	Label       string   `json:"label"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// Line returns the line the label refers to.
+func (l *PinLabel) Line() Line {
+	return Line{Chip: l.Chip, Offset: l.Pin}
+}
+
+// Validation validates the PinLabel structure.
+func (l *PinLabel) Validation() error {
+	if !labelRegex.MatchString(l.Label) {
+		return errors.New("invalid label, start with a letter and use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if len(l.Description) > 256 {
+		return errors.New("description too long, use up to 256 characters")
+	}
+	return nil
+}
+
+// Pulse describes a timed pulse, or a blink pattern when Repeat is greater than one.
+type Pulse struct {
+	Duration string `json:"duration"` // how long the pin leaves its resting level, e.g. 2s
+	Repeat   int    `json:"repeat"`   // number of pulses, defaults to 1
+	Interval string `json:"interval"` // time at the resting level between pulses
+}
+
+// Validation validates the Pulse structure.
+func (p *Pulse) Validation() error {
+	duration, interval, err := p.Periods()
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return errors.New("invalid duration, use a positive duration such as '2s'")
+	}
+	if p.Repeat < 0 {
+		return errors.New("invalid repeat, use a positive number")
+	}
+	if p.Repeat > 1 && interval <= 0 {
+		return errors.New("invalid interval, a positive interval is required to repeat")
+	}
+	return nil
+}
+
+// Periods returns the parsed pulse duration and interval.
+func (p *Pulse) Periods() (duration, interval time.Duration, err error) {
+	if duration, err = time.ParseDuration(p.Duration); err != nil {
+		return 0, 0, errors.New("invalid duration, use a duration such as '2s'")
+	}
+	if len(p.Interval) > 0 {
+		if interval, err = time.ParseDuration(p.Interval); err != nil {
+			return 0, 0, errors.New("invalid interval, use a duration such as '500ms'")
+		}
+	}
+	return duration, interval, nil
+}
+
+// Lease arms the watchdog of an output pin: unless renewed within Timeout the
+// pin is driven to its safe value.
+type Lease struct {
+	Chip      string `json:"chip"`
+	Pin       int    `json:"pin"`
+	Timeout   string `json:"timeout"`    // e.g. 30s
+	SafeValue *int   `json:"safe_value"` // defaults to the safe_value of the pin
+}
+
+// MinLeaseTimeout is the shortest watchdog timeout accepted.
+const MinLeaseTimeout = time.Second
+
+// Validation validates the Lease structure.
+func (l *Lease) Validation() error {
+	if _, err := l.TimeoutPeriod(); err != nil {
+		return err
+	}
+	if l.SafeValue != nil && *l.SafeValue != 0 && *l.SafeValue != 1 {
+		return errors.New("invalid safe value, use 0 or 1")
+	}
+	return nil
+}
+
+// TimeoutPeriod returns the parsed lease timeout.
+func (l *Lease) TimeoutPeriod() (time.Duration, error) {
+	d, err := time.ParseDuration(l.Timeout)
+	if err != nil || d < MinLeaseTimeout {
+		return 0, errors.New("invalid timeout, use a duration of at least '1s'")
+	}
+	return d, nil
+}
+
+// Line returns the line the lease refers to.
+func (l *Lease) Line() Line {
+	return Line{Chip: l.Chip, Offset: l.Pin}
+}
+
+// SimulatedInput is the level an external device drives a simulated input to.
+type SimulatedInput struct {
+	Value int `json:"value"` // physical level, 0 or 1
+}
+
+// Interlock is a group of output pins of which at most one may be active at a
+// time, e.g. the two direction relays of a motor.
+type Interlock struct {
+	Name     string   `json:"name"`
+	Pins     []string `json:"pins"`                // pin references, resolved into Lines when stored
+	DeadTime string   `json:"dead_time,omitempty"` // minimum time between a pin going inactive and another going active, e.g. 100ms
+	Lines    []Line   `json:"lines"`
+}
+
+// Validation validates the Interlock structure.
+func (i *Interlock) Validation() error {
+	if !tokenNameRegex.MatchString(i.Name) {
+		return errors.New("invalid name, use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if len(i.Pins) < 2 {
+		return errors.New("an interlock needs at least two pins")
+	}
+	if _, err := i.DeadTimePeriod(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeadTimePeriod returns the parsed dead time, zero if none is set.
+func (i *Interlock) DeadTimePeriod() (time.Duration, error) {
+	if i.DeadTime == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(i.DeadTime)
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid dead_time, use a duration such as '100ms'")
+	}
+	return d, nil
+}
+
 // Validation validates the PinMode structure.
 func (p *PinMode) Validation() error {
-	if len(p.Direction) > 0 && p.Direction != Input && p.Direction != Output {
-		return errors.New("invalid direction, use 'in' or 'out'")
+	if len(p.Direction) > 0 && p.Direction != Input && p.Direction != Output && p.Direction != PWM {
+		return errors.New("invalid direction, use 'in', 'out' or 'pwm'")
+	}
+	if p.Direction == PWM {
+		if p.Frequency <= 0 || p.Frequency > MaxSoftPWMFrequency {
+			return errors.New("invalid frequency, use a value between 0 and 1000 Hz")
+		}
+		if p.DutyCycle < 0 || p.DutyCycle > 100 {
+			return errors.New("invalid duty cycle, use a value between 0 and 100")
+		}
 	}
 	if len(p.Active) > 0 && p.Active != Low && p.Active != High {
 		return errors.New("invalid active state, use 'low' or 'high'")
 	}
+	if len(p.Bias) > 0 && p.Bias != PullUp && p.Bias != PullDown && p.Bias != BiasDisabled {
+		return errors.New("invalid bias, use 'pull-up', 'pull-down' or 'disabled'")
+	}
+	if len(p.Drive) > 0 && p.Drive != PushPull && p.Drive != OpenDrain && p.Drive != OpenSource {
+		return errors.New("invalid drive, use 'push-pull', 'open-drain' or 'open-source'")
+	}
+	if len(p.Drive) > 0 && p.Direction != Output && p.Direction != PWM {
+		return errors.New("drive is only valid for output and pwm pins")
+	}
+	if len(p.Restore) > 0 && p.Restore != RestoreLast && p.Restore != RestoreSafe && p.Restore != RestoreNone {
+		return errors.New("invalid restore policy, use 'last', 'safe' or 'none'")
+	}
+	if p.SafeValue != 0 && p.SafeValue != 1 {
+		return errors.New("invalid safe value, use 0 or 1")
+	}
+	return nil
+}
+
+// PWMChannel is the configuration of a hardware PWM channel.
+type PWMChannel struct {
+	Chip      int    `json:"chip"`
+	Channel   int    `json:"channel"`
+	Period    int64  `json:"period"`     // nanoseconds
+	DutyCycle int64  `json:"duty_cycle"` // nanoseconds
+	Polarity  string `json:"polarity"`   // normal or inversed
+	Enable    bool   `json:"enable"`
+	Restore   string `json:"restore"` // last, safe or none
+}
+
+// Valid PWM polarity constants.
+const (
+	PolarityNormal   = "normal"
+	PolarityInversed = "inversed"
+)
+
+// Validation validates the PWMChannel structure.
+func (p *PWMChannel) Validation() error {
+	if p.Period <= 0 {
+		return errors.New("invalid period, use a positive number of nanoseconds")
+	}
+	if p.DutyCycle < 0 || p.DutyCycle > p.Period {
+		return errors.New("invalid duty cycle, use a value between 0 and the period")
+	}
+	if len(p.Polarity) > 0 && p.Polarity != PolarityNormal && p.Polarity != PolarityInversed {
+		return errors.New("invalid polarity, use 'normal' or 'inversed'")
+	}
+	if len(p.Restore) > 0 && p.Restore != RestoreLast && p.Restore != RestoreSafe && p.Restore != RestoreNone {
+		return errors.New("invalid restore policy, use 'last', 'safe' or 'none'")
+	}
+	return nil
+}
+
+// Token scopes. A write scope also grants the read scope of the same area and
+// ScopeAdmin grants every scope.
+const (
+	ScopeAdmin      = "admin"
+	ScopeInfoRead   = "info:read"
+	ScopeGpioRead   = "gpio:read"
+	ScopeGpioWrite  = "gpio:write"
+	ScopePwmRead    = "pwm:read"
+	ScopePwmWrite   = "pwm:write"
+	ScopeShareRead  = "share:read"
+	ScopeShareWrite = "share:write"
+	ScopePsRead     = "ps:read"
+	ScopePsKill     = "ps:kill"
+	ScopeRulesRead  = "rules:read"
+	ScopeRulesWrite = "rules:write"
+	ScopeI2CRead    = "i2c:read"
+	ScopeI2CWrite   = "i2c:write"
+)
+
+// Scopes lists every valid token scope.
+var Scopes = []string{
+	ScopeAdmin, ScopeInfoRead,
+	ScopeGpioRead, ScopeGpioWrite,
+	ScopePwmRead, ScopePwmWrite,
+	ScopeShareRead, ScopeShareWrite,
+	ScopePsRead, ScopePsKill,
+	ScopeRulesRead, ScopeRulesWrite,
+	ScopeI2CRead, ScopeI2CWrite,
+}
+
+// IsReadScope reports whether scope only grants read access.
+func IsReadScope(scope string) bool {
+	return strings.HasSuffix(scope, ":read")
+}
+
+// Token is a named API token. Its secret is never stored, only its hash.
+type Token struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	LastUsed  *time.Time `json:"last_used"`
+}
+
+// Expired reports whether the token expired at now.
+func (t *Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+// Allows reports whether the token grants scope.
+func (t *Token) Allows(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+		if IsReadScope(scope) && s == strings.TrimSuffix(scope, ":read")+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+var tokenNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+// TokenRequest holds the parameters to create a Token.
+type TokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn string   `json:"expires_in"` // e.g. 720h, empty for no expiry
+}
+
+// Validation validates the TokenRequest structure.
+func (t *TokenRequest) Validation() error {
+	if !tokenNameRegex.MatchString(t.Name) {
+		return errors.New("invalid name, use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if len(t.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range t.Scopes {
+		valid := false
+		for _, s := range Scopes {
+			valid = valid || s == scope
+		}
+		if !valid {
+			return errors.New("invalid scope '" + scope + "', use one of " + strings.Join(Scopes, ", "))
+		}
+	}
+	if _, err := t.Expiry(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Expiry returns the parsed expiry duration, zero if the token does not expire.
+func (t *TokenRequest) Expiry() (time.Duration, error) {
+	if len(t.ExpiresIn) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(t.ExpiresIn)
+	if err != nil || d <= 0 {
+		return 0, errors.New("invalid expires_in, use a positive duration such as '720h'")
+	}
+	return d, nil
+}
+
+// Rule trigger types.
+const (
+	TriggerEdge     = "edge"     // an edge on an input pin
+	TriggerMetric   = "metric"   // a system metric crossing a threshold
+	TriggerSchedule = "schedule" // a cron expression
+)
+
+// Rule action types.
+const (
+	ActionSet     = "set"     // configure a pin
+	ActionPulse   = "pulse"   // pulse an output pin
+	ActionKill    = "kill"    // kill a process
+	ActionWebhook = "webhook" // POST the firing to a URL
+)
+
+// Metric comparison operators.
+var Operators = []string{">", ">=", "<", "<="}
+
+// Rule fires its actions, in order, whenever its trigger is met.
+type Rule struct {
+	Name     string       `json:"name"`
+	Disabled bool         `json:"disabled"`
+	Trigger  RuleTrigger  `json:"trigger"`
+	Actions  []RuleAction `json:"actions"`
+	Cooldown string       `json:"cooldown,omitempty"` // minimum time between firings, e.g. 1m
+}
+
+// RuleTrigger is the condition firing a Rule. Only the fields of its type are used.
+type RuleTrigger struct {
+	Type string `json:"type"` // edge, metric or schedule
+
+	// edge
+	Pin      string `json:"pin,omitempty"`      // offset, chip:offset, label or line name
+	Edge     string `json:"edge,omitempty"`     // rising, falling or both
+	Debounce string `json:"debounce,omitempty"` // e.g. 10ms
+
+	// metric, fires when the comparison becomes true
+	Metric    string  `json:"metric,omitempty"`   // e.g. cpu_temp
+	Operator  string  `json:"operator,omitempty"` // >, >=, < or <=
+	Threshold float64 `json:"threshold,omitempty"`
+	Interval  string  `json:"interval,omitempty"` // how often the metric is read, default 10s
+
+	// schedule
+	Cron string `json:"cron,omitempty"` // e.g. "0 6 * * 1-5"
+}
+
+// RuleAction is an action run when a Rule fires. Only the fields of its type are used.
+type RuleAction struct {
+	Type string `json:"type"` // set, pulse, kill or webhook
+
+	Pin   string   `json:"pin,omitempty"`   // set and pulse: offset, chip:offset, label or line name
+	Mode  *PinMode `json:"mode,omitempty"`  // set
+	Pulse *Pulse   `json:"pulse,omitempty"` // pulse
+
+	PID     int    `json:"pid,omitempty"`     // kill: process id
+	Process string `json:"process,omitempty"` // kill: command name, every matching process is killed
+
+	URL string `json:"url,omitempty"` // webhook
+}
+
+// RuleFiring is the audit record of a Rule firing.
+type RuleFiring struct {
+	Rule    string             `json:"rule"`
+	Time    time.Time          `json:"time"`
+	Trigger string             `json:"trigger"` // what fired the rule
+	Actions []RuleActionResult `json:"actions"`
+}
+
+// RuleActionResult is the outcome of an action of a RuleFiring.
+type RuleActionResult struct {
+	Type  string `json:"type"`
+	Error string `json:"error,omitempty"`
+}
+
+var ruleNameRegex = tokenNameRegex
+
+// Validation validates the Rule structure.
+func (r *Rule) Validation() error {
+	if !ruleNameRegex.MatchString(r.Name) {
+		return errors.New("invalid name, use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if err := r.Trigger.Validation(); err != nil {
+		return err
+	}
+	if len(r.Actions) == 0 {
+		return errors.New("at least one action is required")
+	}
+	for i := range r.Actions {
+		if err := r.Actions[i].Validation(); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+	if _, err := r.CooldownPeriod(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CooldownPeriod returns the parsed cooldown, zero if not set.
+func (r *Rule) CooldownPeriod() (time.Duration, error) {
+	if len(r.Cooldown) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.Cooldown)
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid cooldown, use a duration such as '1m'")
+	}
+	return d, nil
+}
+
+// Validation validates the RuleTrigger structure. Metric names and cron
+// expressions are checked by the rules engine.
+func (t *RuleTrigger) Validation() error {
+	switch t.Type {
+	case TriggerEdge:
+		if len(t.Pin) == 0 {
+			return errors.New("edge trigger requires a pin")
+		}
+		sub := EventSubscription{Edge: t.Edge, Debounce: t.Debounce}
+		return sub.Validation()
+	case TriggerMetric:
+		if len(t.Metric) == 0 {
+			return errors.New("metric trigger requires a metric")
+		}
+		valid := false
+		for _, op := range Operators {
+			valid = valid || op == t.Operator
+		}
+		if !valid {
+			return errors.New("invalid operator, use one of " + strings.Join(Operators, ", "))
+		}
+		if _, err := t.IntervalPeriod(); err != nil {
+			return err
+		}
+	case TriggerSchedule:
+		if len(t.Cron) == 0 {
+			return errors.New("schedule trigger requires a cron expression")
+		}
+	default:
+		return errors.New("invalid trigger type, use 'edge', 'metric' or 'schedule'")
+	}
+	return nil
+}
+
+// IntervalPeriod returns how often a metric trigger is evaluated.
+func (t *RuleTrigger) IntervalPeriod() (time.Duration, error) {
+	if len(t.Interval) == 0 {
+		return 10 * time.Second, nil
+	}
+	d, err := time.ParseDuration(t.Interval)
+	if err != nil || d < time.Second {
+		return 0, errors.New("invalid interval, use a duration of at least '1s'")
+	}
+	return d, nil
+}
+
+// Compare reports whether value meets the threshold of a metric trigger.
+func (t *RuleTrigger) Compare(value float64) bool {
+	switch t.Operator {
+	case ">":
+		return value > t.Threshold
+	case ">=":
+		return value >= t.Threshold
+	case "<":
+		return value < t.Threshold
+	case "<=":
+		return value <= t.Threshold
+	}
+	return false
+}
+
+// Validation validates the RuleAction structure.
+func (a *RuleAction) Validation() error {
+	switch a.Type {
+	case ActionSet:
+		if len(a.Pin) == 0 || a.Mode == nil {
+			return errors.New("set action requires a pin and a mode")
+		}
+		if len(a.Mode.Direction) == 0 {
+			return errors.New("set action requires a mode direction")
+		}
+		return a.Mode.Validation()
+	case ActionPulse:
+		if len(a.Pin) == 0 || a.Pulse == nil {
+			return errors.New("pulse action requires a pin and a pulse")
+		}
+		return a.Pulse.Validation()
+	case ActionKill:
+		if (a.PID <= 0) == (len(a.Process) == 0) {
+			return errors.New("kill action requires either a pid or a process")
+		}
+	case ActionWebhook:
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook action requires an http or https url")
+		}
+	default:
+		return errors.New("invalid action type, use 'set', 'pulse', 'kill' or 'webhook'")
+	}
+	return nil
+}
+
+// Scope returns the token scope needed to save the action, empty if the
+// scope of the rules is enough.
+func (a *RuleAction) Scope() string {
+	switch a.Type {
+	case ActionSet, ActionPulse:
+		return ScopeGpioWrite
+	case ActionKill:
+		return ScopePsKill
+	}
+	return ""
+}
+
+// Schedule configures pins at the times of a cron expression.
+type Schedule struct {
+	Name     string    `json:"name"`
+	Cron     string    `json:"cron"` // standard five field expression, e.g. "0 6 * * 1-5"
+	Actions  []PinMode `json:"actions"`
+	Disabled bool      `json:"disabled"`
+}
+
+// ScheduleRun is the outcome of the last runs of a Schedule.
+type ScheduleRun struct {
+	LastRun   *time.Time `json:"last_run"`
+	LastError string     `json:"last_error,omitempty"`
+	Runs      int        `json:"runs"`
+	Failures  int        `json:"failures"`
+}
+
+// Validation validates the Schedule structure. The cron expression is checked
+// by the scheduler.
+func (s *Schedule) Validation() error {
+	if !ruleNameRegex.MatchString(s.Name) {
+		return errors.New("invalid name, use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if len(s.Cron) == 0 {
+		return errors.New("a cron expression is required")
+	}
+	if len(s.Actions) == 0 {
+		return errors.New("at least one action is required")
+	}
+	for i := range s.Actions {
+		if len(s.Actions[i].Direction) == 0 {
+			return fmt.Errorf("action %d: a direction is required", i+1)
+		}
+		if err := s.Actions[i].Validation(); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Sources of a pin change.
+const (
+	SourceAPI      = "api"      // a client of the API
+	SourceStartup  = "startup"  // the restore of the stored pins
+	SourceSchedule = "schedule" // a schedule
+	SourceRule     = "rule"     // a rule
+	SourceWatchdog = "watchdog" // an expired watchdog
+	SourceMQTT     = "mqtt"     // a command received through the MQTT bridge
+)
+
+// Source identifies who changed a pin.
+type Source struct {
+	Type  string `json:"type"`
+	IP    string `json:"ip,omitempty"`    // client address, for api
+	Token string `json:"token,omitempty"` // token name, for api
+	Name  string `json:"name,omitempty"`  // schedule or rule name
+}
+
+// Pin change actions.
+const (
+	ChangeSet      = "set"      // a pin configured on its own
+	ChangeBatch    = "batch"    // a pin configured in a batch
+	ChangePulse    = "pulse"    // a pulse started on a pin
+	ChangeRestore  = "restore"  // a pin restored on startup
+	ChangeWatchdog = "watchdog" // a pin driven to its safe value by its watchdog
+)
+
+// PinChange is an entry of the audit log of the pins.
+type PinChange struct {
+	ID     string    `json:"id"` // time ordered, used as pagination cursor
+	Time   time.Time `json:"time"`
+	Chip   string    `json:"chip"`
+	Pin    int       `json:"pin"`
+	Action string    `json:"action"`
+	Old    *PinMode  `json:"old"` // nil if the pin was not held
+	New    PinMode   `json:"new"`
+	Detail string    `json:"detail,omitempty"`
+	Source Source    `json:"source"`
+}
+
+// Line returns the line the change refers to.
+func (p *PinChange) Line() Line {
+	return Line{Chip: p.Chip, Offset: p.Pin}
+}
+
+// AuditQuery selects entries of the audit log, newest first.
+type AuditQuery struct {
+	Line   *Line     // only the changes of a line if set
+	From   time.Time // zero for no lower bound
+	To     time.Time // zero for no upper bound
+	Before string    // only the entries older than this ID, for the next page
+	Limit  int
+}
+
+// MaxAuditLimit is the largest page of the audit log.
+const MaxAuditLimit = 1000
+
+// MetricPoint is a sample of the metric history, or the aggregate of the
+// samples of a step.
+type MetricPoint struct {
+	Time  time.Time `json:"time"` // start of the step
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"` // number of samples aggregated
+}
+
+// MetricQuery selects a range of the history of a metric.
+type MetricQuery struct {
+	Metric string
+	From   time.Time
+	To     time.Time
+	Step   time.Duration // zero for the resolution of the stored samples
+}
+
+// Alert states.
+const (
+	AlertInactive = "inactive" // the condition is not met
+	AlertPending  = "pending"  // the condition is met, for less than the for duration
+	AlertFiring   = "firing"   // the condition has been met for the for duration
+	AlertResolved = "resolved" // status of the notification of an alert that stopped firing
+)
+
+// AlertRule raises an alert while a metric is past a threshold.
+type AlertRule struct {
+	Name       string   `json:"name"`
+	Disabled   bool     `json:"disabled"`
+	Metric     string   `json:"metric"`   // a metric of the rules, or a get_throttled flag such as under_voltage
+	Operator   string   `json:"operator"` // >, >=, < or <=
+	Threshold  float64  `json:"threshold"`
+	Hysteresis float64  `json:"hysteresis,omitempty"` // how far back past the threshold the metric must go to resolve
+	For        string   `json:"for,omitempty"`        // how long the condition must hold to fire, e.g. 5m
+	Interval   string   `json:"interval,omitempty"`   // how often the metric is read, default 30s
+	Severity   string   `json:"severity,omitempty"`   // e.g. warning or critical, passed to the notifiers
+	Notifiers  []string `json:"notifiers,omitempty"`  // names of the notifiers, every notifier if empty
+}
+
+// AlertState is the state of an AlertRule.
+type AlertState struct {
+	State      string     `json:"state"` // inactive, pending or firing
+	Since      time.Time  `json:"since"` // when the state was entered
+	Value      *float64   `json:"value"` // last value read, nil if never read
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Error      string     `json:"error,omitempty"`        // error reading the metric
+	NotifyErr  string     `json:"notify_error,omitempty"` // error of the last notification
+}
+
+// AlertNotification is sent to the notifiers when an alert fires or resolves.
+type AlertNotification struct {
+	Alert     string    `json:"alert"`
+	Status    string    `json:"status"` // firing or resolved
+	Severity  string    `json:"severity,omitempty"`
+	Host      string    `json:"host"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Time      time.Time `json:"time"`
+	Test      bool      `json:"test,omitempty"` // sent from the API to check a notifier
+}
+
+var alertNameRegex = tokenNameRegex
+
+// Validation validates the AlertRule structure. Metric and notifier names
+// are checked by the alerting engine.
+func (a *AlertRule) Validation() error {
+	if !alertNameRegex.MatchString(a.Name) {
+		return errors.New("invalid name, use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if len(a.Metric) == 0 {
+		return errors.New("alert requires a metric")
+	}
+	valid := false
+	for _, op := range Operators {
+		valid = valid || op == a.Operator
+	}
+	if !valid {
+		return errors.New("invalid operator, use one of " + strings.Join(Operators, ", "))
+	}
+	if a.Hysteresis < 0 {
+		return errors.New("invalid hysteresis, it must not be negative")
+	}
+	if _, err := a.ForPeriod(); err != nil {
+		return err
+	}
+	if _, err := a.IntervalPeriod(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ForPeriod returns how long the condition must hold to fire, zero if not set.
+func (a *AlertRule) ForPeriod() (time.Duration, error) {
+	if len(a.For) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(a.For)
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid for, use a duration such as '5m'")
+	}
+	return d, nil
+}
+
+// IntervalPeriod returns how often the metric of the alert is read.
+func (a *AlertRule) IntervalPeriod() (time.Duration, error) {
+	if len(a.Interval) == 0 {
+		return 30 * time.Second, nil
+	}
+	d, err := time.ParseDuration(a.Interval)
+	if err != nil || d < time.Second {
+		return 0, errors.New("invalid interval, use a duration of at least '1s'")
+	}
+	return d, nil
+}
+
+// Compare reports whether value meets the condition of the alert.
+func (a *AlertRule) Compare(value float64) bool {
+	trigger := RuleTrigger{Operator: a.Operator, Threshold: a.Threshold}
+	return trigger.Compare(value)
+}
+
+// Resolved reports whether value is back past the threshold by the hysteresis,
+// so that a firing alert resolves.
+func (a *AlertRule) Resolved(value float64) bool {
+	switch a.Operator {
+	case ">", ">=":
+		return !a.Compare(value + a.Hysteresis)
+	default:
+		return !a.Compare(value - a.Hysteresis)
+	}
+}
+
+// I2CAddr is a 7-bit I2C address, written as "0x76" in JSON. A plain number
+// is accepted too.
+type I2CAddr uint16
+
+// I2C addresses that may be used by devices, the others being reserved.
+const (
+	I2CAddrFirst I2CAddr = 0x08
+	I2CAddrLast  I2CAddr = 0x77
+)
+
+// ParseI2CAddr parses an address written in decimal or, with the 0x prefix, in hexadecimal.
+func ParseI2CAddr(s string) (I2CAddr, error) {
+	n, err := strconv.ParseUint(s, 0, 16)
+	if err != nil || I2CAddr(n) < I2CAddrFirst || I2CAddr(n) > I2CAddrLast {
+		return 0, fmt.Errorf("invalid I2C address %q, use 0x08 to 0x77", s)
+	}
+	return I2CAddr(n), nil
+}
+
+func (a I2CAddr) String() string {
+	return fmt.Sprintf("0x%02x", uint16(a))
+}
+
+func (a I2CAddr) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(a.String())), nil
+}
+
+func (a *I2CAddr) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	addr, err := ParseI2CAddr(s)
+	if err != nil {
+		return err
+	}
+	*a = addr
+	return nil
+}
+
+// I2CDevice is a device on an I2C bus decoded by a driver.
+type I2CDevice struct {
+	Name    string             `json:"name"`
+	Bus     int                `json:"bus"`               // number of /dev/i2c-<bus>
+	Address I2CAddr            `json:"address"`           // default address of the driver if empty
+	Driver  string             `json:"driver"`            // bme280, ina219 or pcf8574
+	Options map[string]float64 `json:"options,omitempty"` // driver settings, e.g. shunt_ohms for ina219
+}
+
+var i2cDeviceNameRegex = tokenNameRegex
+
+// Validation validates the I2CDevice structure. The driver and its options
+// are checked by the I2C subsystem.
+func (d *I2CDevice) Validation() error {
+	if !i2cDeviceNameRegex.MatchString(d.Name) {
+		return errors.New("invalid name, use up to 64 letters, digits, '.', '_' or '-'")
+	}
+	if d.Bus < 0 {
+		return errors.New("invalid bus, use the number of /dev/i2c-<bus>")
+	}
+	if d.Address != 0 && (d.Address < I2CAddrFirst || d.Address > I2CAddrLast) {
+		return errors.New("invalid address, use 0x08 to 0x77")
+	}
+	if len(d.Driver) == 0 {
+		return errors.New("device requires a driver")
+	}
+	return nil
+}
+
+// I2CReading is a value decoded from a device.
+type I2CReading struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// I2CMaxTransfer is the largest number of bytes written after the register, or
+// read, in one transaction.
+const I2CMaxTransfer = 256
+
+// I2CTransfer is a raw transaction with a device: the register, if any, and
+// Write are written, then Read bytes are read after a repeated start.
+type I2CTransfer struct {
+	Register *int  `json:"register,omitempty"` // register selected before writing or reading
+	Write    []int `json:"write,omitempty"`    // bytes written after the register
+	Read     int   `json:"read,omitempty"`     // number of bytes read
+}
+
+// Validation validates the I2CTransfer structure.
+func (t *I2CTransfer) Validation() error {
+	if t.Register != nil && (*t.Register < 0 || *t.Register > 0xff) {
+		return errors.New("invalid register, use 0 to 255")
+	}
+	for _, b := range t.Write {
+		if b < 0 || b > 0xff {
+			return errors.New("invalid byte to write, use 0 to 255")
+		}
+	}
+	if len(t.Write) > I2CMaxTransfer || t.Read < 0 || t.Read > I2CMaxTransfer {
+		return fmt.Errorf("invalid transfer, write or read up to %d bytes", I2CMaxTransfer)
+	}
+	if t.Register == nil && len(t.Write) == 0 && t.Read == 0 {
+		return errors.New("transfer requires a register, bytes to write or bytes to read")
+	}
 	return nil
 }
+
+// Bytes returns the bytes written by the transfer, register first.
+func (t *I2CTransfer) Bytes() []byte {
+	var w []byte
+	if t.Register != nil {
+		w = append(w, byte(*t.Register))
+	}
+	for _, b := range t.Write {
+		w = append(w, byte(b))
+	}
+	return w
+}
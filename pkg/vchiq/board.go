@@ -0,0 +1,53 @@
+package vchiq
+
+import "errors"
+
+// HeaderPin describes a pin of the GPIO header.
+type HeaderPin struct {
+	Physical int    `json:"physical"`
+	BCM      int    `json:"bcm"`      // GPIO line offset, -1 for power and ground pins
+	Function string `json:"function"` // 3V3, 5V, GND or GPIO
+}
+
+// header40 maps the physical pins of the 40 pin header to their BCM numbers.
+// Pins missing here are power or ground.
+var header40 = map[int]int{
+	3: 2, 5: 3, 7: 4, 8: 14, 10: 15, 11: 17, 12: 18, 13: 27,
+	15: 22, 16: 23, 18: 24, 19: 10, 21: 9, 22: 25, 23: 11, 24: 8,
+	26: 7, 27: 0, 28: 1, 29: 5, 31: 6, 32: 12, 33: 13, 35: 19,
+	36: 16, 37: 26, 38: 20, 40: 21,
+}
+
+var header40Power = map[int]string{
+	1: "3V3", 17: "3V3",
+	2: "5V", 4: "5V",
+	6: "GND", 9: "GND", 14: "GND", 20: "GND", 25: "GND", 30: "GND", 34: "GND", 39: "GND",
+}
+
+// Boards with the 40 pin header. Compute modules expose their GPIOs on the carrier board instead.
+var header40Boards = map[string]bool{
+	RpiZero: true, RpiZeroW: true, RpiZero2W: true,
+	Rpi2B: true, Rpi2B + " (with BCM2837)": true,
+	Rpi3APlus: true, Rpi3B: true, Rpi3BPlus: true,
+	Rpi4B: true, Rpi400: true, Rpi5: true,
+}
+
+// ErrNoHeader is returned for boards without a known GPIO header.
+var ErrNoHeader = errors.New("board has no known GPIO header")
+
+// GetHeader returns the GPIO header layout of a board, as named by GetDeviceName.
+func GetHeader(deviceName string) ([]HeaderPin, error) {
+	if !header40Boards[deviceName] {
+		return nil, ErrNoHeader
+	}
+
+	pins := make([]HeaderPin, 0, 40)
+	for physical := 1; physical <= 40; physical++ {
+		if bcm, ok := header40[physical]; ok {
+			pins = append(pins, HeaderPin{Physical: physical, BCM: bcm, Function: "GPIO"})
+		} else {
+			pins = append(pins, HeaderPin{Physical: physical, BCM: -1, Function: header40Power[physical]})
+		}
+	}
+	return pins, nil
+}
@@ -0,0 +1,170 @@
+package vchiq
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Names of the numeric metrics read by ReadMetric.
+const (
+	MetricCPUTemp   = "cpu_temp"  // °C
+	MetricGPUTemp   = "gpu_temp"  // °C
+	MetricCoreVolt  = "core_volt" // V
+	MetricCPUFreq   = "cpu_freq"  // MHz
+	MetricLoad1     = "load1"     // 1-minute load average
+	MetricMemUsed   = "mem_used"  // percent
+	MetricDiskRoot  = "disk_root" // percent of / in use
+	MetricDiskBoot  = "disk_boot" // percent of /boot in use
+	MetricDiskHome  = "disk_home" // percent of /home in use
+	MetricThrottled = "throttled" // get_throttled bit mask
+)
+
+// Metrics lists every metric accepted by ReadMetric.
+var Metrics = []string{
+	MetricCPUTemp, MetricGPUTemp, MetricCoreVolt, MetricCPUFreq,
+	MetricLoad1, MetricMemUsed, MetricDiskRoot, MetricDiskBoot, MetricDiskHome, MetricThrottled,
+}
+
+// ErrUnknownMetric is returned by ReadMetric for names not in Metrics.
+var ErrUnknownMetric = errors.New("unknown metric")
+
+// ReadMetric returns the current value of a metric as a number.
+func ReadMetric(name string) (float64, error) {
+	switch name {
+	case MetricCPUTemp:
+		return parseMetric(GetCPUTemp())
+	case MetricGPUTemp:
+		return parseMetric(GetGPUTemp())
+	case MetricCoreVolt:
+		return parseMetric(GetCoreVolt())
+	case MetricCPUFreq:
+		return GetCPUCurrFreq()
+	case MetricLoad1:
+		load1, _, _, err := GetLoadAverages()
+		return load1, err
+	case MetricMemUsed:
+		used, err := GetMemoryUsagePercent()
+		return used * 100, err
+	case MetricDiskRoot:
+		used, err := calculateDiskUsage("/", true)
+		return used * 100, err
+	case MetricDiskBoot:
+		used, err := calculateDiskUsage("/boot", true)
+		return used * 100, err
+	case MetricDiskHome:
+		used, err := calculateDiskUsage("/home", true)
+		return used * 100, err
+	case MetricThrottled:
+		throttled, err := GetThrottled()
+		return float64(throttled), err
+	default:
+		return 0, ErrUnknownMetric
+	}
+}
+
+// parseMetric converts the string returned by the Get functions, such as
+// "45.00C", into a number.
+func parseMetric(value string, err error) (float64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimRight(value, "CV'"), 64)
+}
+
+// ErrUnknownFlag is returned by ReadFlag for names not in ThrottledFlags.
+var ErrUnknownFlag = errors.New("unknown throttled flag")
+
+// ReadFlag returns 1 if the get_throttled bit name is set, 0 otherwise.
+func ReadFlag(name string) (float64, error) {
+	for _, flag := range ThrottledFlags {
+		if flag.Name != name {
+			continue
+		}
+		throttled, err := GetThrottled()
+		if err != nil {
+			return 0, err
+		}
+		if throttled&flag.Bit != 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, ErrUnknownFlag
+}
+
+// ThrottledFlag names a bit of the get_throttled mask.
+type ThrottledFlag struct {
+	Name string
+	Bit  int64
+}
+
+// ThrottledFlags lists every bit of the get_throttled mask.
+var ThrottledFlags = []ThrottledFlag{
+	{"under_voltage", UnderVoltage},
+	{"freq_capped", FreqCap},
+	{"throttled", Throttling},
+	{"soft_temp_limit", SoftTempLimitActive},
+	{"under_voltage_occurred", UnderVoltageOccurred},
+	{"freq_capped_occurred", FreqCapOccurred},
+	{"throttled_occurred", Throttled},
+	{"soft_temp_limit_occurred", SoftTempLimitOccurred},
+}
+
+// DiskMounts are the mount points reported by the disk functions.
+var DiskMounts = []string{"/boot", "/", "/home"}
+
+// GetMemSplit returns the memory given to the ARM cores and to the GPU, in bytes.
+func GetMemSplit() (arm, gpu float64, err error) {
+	armSize, gpuSize, err := GetMem()
+	if err != nil {
+		return 0, 0, err
+	}
+	if arm, err = parseMemorySize(armSize); err != nil {
+		return 0, 0, err
+	}
+	if gpu, err = parseMemorySize(gpuSize); err != nil {
+		return 0, 0, err
+	}
+	return arm, gpu, nil
+}
+
+// parseMemorySize converts a size such as "948M", as returned by GetMem, into bytes.
+func parseMemorySize(size string) (float64, error) {
+	if size == "" {
+		return 0, errors.New("empty memory size")
+	}
+	value, err := strconv.ParseFloat(size[:len(size)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	switch size[len(size)-1] {
+	case 'K':
+		return value * (1 << 10), nil
+	case 'M':
+		return value * (1 << 20), nil
+	case 'G':
+		return value * (1 << 30), nil
+	}
+	return 0, errors.New("unknown memory size unit in " + size)
+}
+
+// GetLoadAverages returns the 1, 5 and 15 minute load averages.
+func GetLoadAverages() (load1, load5, load15 float64, err error) {
+	content, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 {
+		return 0, 0, 0, errors.New("unexpected /proc/loadavg format")
+	}
+	loads := make([]float64, 3)
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return loads[0], loads[1], loads[2], nil
+}
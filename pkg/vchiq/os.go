@@ -97,15 +97,15 @@ func RetrieveDiskUsage() (int, int, int, error) {
 
 // RetrieveDiskTotal returns the total disk space of boot, root, and home partitions in bytes.
 func RetrieveDiskTotal() (int, int, int, error) {
-	bootTotal, _, _, err := getDiskSize("/boot")
+	bootTotal, _, _, err := GetDiskSize("/boot")
 	if err != nil {
 		return 0, 0, 0, err
 	}
-	rootTotal, _, _, err := getDiskSize("/")
+	rootTotal, _, _, err := GetDiskSize("/")
 	if err != nil {
 		return 0, 0, 0, err
 	}
-	homeTotal, _, _, err := getDiskSize("/home")
+	homeTotal, _, _, err := GetDiskSize("/home")
 	if err != nil {
 		return 0, 0, 0, err
 	}
@@ -133,7 +133,8 @@ func calculateDiskUsage(path string, percent bool) (float64, error) {
 	return usedSpace, nil
 }
 
-func getDiskSize(path string) (float64, float64, float64, error) {
+// GetDiskSize returns the total, free and available bytes of the file system mounted at path.
+func GetDiskSize(path string) (float64, float64, float64, error) {
 	var fs syscall.Statfs_t
 
 	if err := syscall.Statfs(path, &fs); err != nil {